@@ -4,6 +4,7 @@ go 1.25.1
 
 require (
 	github.com/goccy/go-graphviz v0.2.9
+	github.com/google/pprof v0.0.0-20260906184651-6331bc6350fe
 	github.com/spf13/cobra v1.10.1
 )
 
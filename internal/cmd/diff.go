@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/supercute/spx-graph/internal/graph"
+	"github.com/supercute/spx-graph/internal/spx"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <base profile> <new profile>",
+	Short: "Compare two SPX profiles",
+	Long: `Build call graphs for a base and a new profile and show the difference.
+Functions are matched by name, red nodes got slower and green nodes got faster.
+
+Examples:
+  spx-graph diff before.txt.gz after.txt.gz
+  spx-graph diff before.txt.gz after.txt.gz -o diff.html`,
+	Args: cobra.ExactArgs(2),
+	RunE: runDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	filter, err := graphFilter()
+	if err != nil {
+		return err
+	}
+
+	_, baseGraph, err := loadProfile(args[0])
+	if err != nil {
+		return err
+	}
+
+	profile, headGraph, err := loadProfile(args[1])
+	if err != nil {
+		return err
+	}
+
+	// Both profiles are filtered before comparing them
+	if baseGraph, err = baseGraph.Filter(filter); err != nil {
+		return err
+	}
+	if headGraph, err = headGraph.Filter(filter); err != nil {
+		return err
+	}
+
+	diffGraph := spx.DiffCallGraphs(baseGraph, headGraph)
+
+	generator := graph.NewGenerator(diffGraph, nil)
+	generator.SetMetadata(profile.Metadata)
+	generator.SetOptions(graphOptions())
+
+	return output(generator)
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/supercute/spx-graph/internal/export"
+	"github.com/supercute/spx-graph/internal/spx"
+)
+
+var (
+	exportFormat string
+	exportMetric string
+)
+
+// exporters by --format value
+var exporters = map[string]func(w io.Writer, profile *spx.Profile) error{
+	"pprof":      export.WritePprof,
+	"trace":      export.WriteTrace,
+	"speedscope": export.WriteSpeedscope,
+	"callgrind":  export.WriteCallgrind,
+	"collapsed": func(w io.Writer, profile *spx.Profile) error {
+		return export.WriteCollapsed(w, profile, exportMetric)
+	},
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export <profile>",
+	Short: "Export SPX profile to other profiler formats",
+	Long: `Convert a SPX profile to a format readable by other tools.
+
+Formats:
+  pprof       gzipped profile.proto for go tool pprof
+  trace       Trace Event Format JSON for Perfetto and chrome://tracing
+  speedscope  speedscope JSON with one profile per metric
+  callgrind   callgrind file for KCachegrind and QCachegrind
+  collapsed   folded stacks for flamegraph.pl and inferno, valued by --metric
+
+Examples:
+  spx-graph export profile.txt.gz -o profile.pb.gz
+  cat profile.txt.gz | spx-graph export - > profile.pb.gz
+  go tool pprof -http=:8081 profile.pb.gz
+  spx-graph export profile.txt.gz --format trace -o trace.json
+  spx-graph export profile.txt.gz --format speedscope -o profile.speedscope.json
+  spx-graph export profile.txt.gz --format callgrind -o callgrind.out.spx
+  spx-graph export profile.txt.gz --format collapsed | flamegraph.pl > flame.svg
+  spx-graph export profile.txt.gz --format collapsed --metric zm > memory.folded`,
+	Args: cobra.ExactArgs(1),
+	RunE: runExport,
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&exportFormat, "format", "pprof", "Export format")
+	exportCmd.Flags().StringVar(&exportMetric, "metric", "wt", "Collapsed stack value: self value of a metric key (wt, zm, ...) or calls")
+	rootCmd.AddCommand(exportCmd)
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	exporter, ok := exporters[exportFormat]
+	if !ok {
+		return fmt.Errorf("unknown export format: %s", exportFormat)
+	}
+
+	profile, err := parseProfile(args[0])
+	if err != nil {
+		return err
+	}
+
+	// Write to stdout unless --output is set
+	if outputFile == "" {
+		return exporter(os.Stdout, profile)
+	}
+
+	file, err := os.Create(outputFile)
+	if err != nil {
+		return fmt.Errorf("cannot create output file: %w", err)
+	}
+	defer file.Close()
+
+	if err := exporter(file, profile); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %s to: %s\n", exportFormat, outputFile)
+	return file.Close()
+}
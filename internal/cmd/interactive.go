@@ -0,0 +1,317 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/supercute/spx-graph/internal/graph"
+	"github.com/supercute/spx-graph/internal/report"
+	"github.com/supercute/spx-graph/internal/server"
+	"github.com/supercute/spx-graph/internal/spx"
+)
+
+var interactiveCmd = &cobra.Command{
+	Use:   "interactive <profile>",
+	Short: "Explore a profile with commands, like go tool pprof",
+	Long: `Parse and analyze a profile once, then read commands from standard input.
+The analyzed call graph stays in memory, filters are applied to it without parsing again.
+Type help at the prompt for the list of commands.
+
+Examples:
+  spx-graph interactive profile.txt.gz
+  spx-graph interactive profile.txt.gz --focus 'Controller::'`,
+	Args: cobra.ExactArgs(1),
+	RunE: runInteractive,
+}
+
+func init() {
+	rootCmd.AddCommand(interactiveCmd)
+}
+
+const interactiveHelp = `Commands:
+  top [n] [regexp]        top functions, like the top subcommand
+  sort <order>            sort top by flat, cum, calls, memory or name
+  list <regexp>           self and inclusive values of matching functions
+  peek <regexp>           callers and callees of matching functions
+  tree [depth]            call paths with their inclusive and self time
+  focus [regexp]          only keep call paths through matching functions
+  ignore [regexp]         drop call paths through matching functions
+  hide [regexp]           hide matching functions, their time goes to callers
+  show [regexp]           hide every function not matching
+  reset                   clear every filter
+  nodecount [n]           show at most n nodes in the graph, 0 shows all
+  nodefraction [f]        hide nodes below this fraction of total time
+  edgefraction [f]        hide edges below this fraction of total time
+  web                     serve the graph of the current view and open it in a browser
+  svg [file]              save the graph of the current view (default profile.svg)
+  help                    show this help
+  quit                    exit
+A filter command without regexp clears that filter, a pruning command without value prints it.`
+
+// session is the state of an interactive run: the analyzed profile and the current view of it
+type session struct {
+	out     io.Writer
+	base    *graph.Generator // generator of the unfiltered call graph
+	view    *graph.Generator // generator restricted by the current filter
+	exprs   map[string]string
+	options graph.Options
+	sort    string
+	server  *server.Server
+}
+
+func runInteractive(cmd *cobra.Command, args []string) error {
+	profile, callGraph, err := loadProfile(args[0])
+	if err != nil {
+		return err
+	}
+
+	base := graph.NewGenerator(callGraph, profile.Functions)
+	base.SetMetadata(profile.Metadata)
+
+	s := &session{
+		out:     os.Stdout,
+		base:    base,
+		exprs:   map[string]string{"focus": focus, "ignore": ignore, "hide": hide, "show": show},
+		options: graphOptions(),
+		sort:    report.SortFlat,
+	}
+	if err := s.applyFilter(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(s.out, `Entering interactive mode (type "help" for commands, "quit" to exit)`)
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Fprint(s.out, "(spx) ")
+		if !scanner.Scan() {
+			fmt.Fprintln(s.out)
+			return scanner.Err()
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		name, arg, _ := strings.Cut(line, " ")
+		if name == "quit" || name == "exit" {
+			return nil
+		}
+		if err := s.run(name, strings.TrimSpace(arg)); err != nil {
+			fmt.Fprintf(s.out, "Error: %v\n", err)
+		}
+	}
+}
+
+// run executes one command of the interactive prompt
+func (s *session) run(name, arg string) error {
+	switch name {
+	case "help":
+		fmt.Fprintln(s.out, interactiveHelp)
+		return nil
+	case "top":
+		return s.top(arg)
+	case "sort":
+		if arg == "" {
+			fmt.Fprintf(s.out, "sort = %s\n", s.sort)
+			return nil
+		}
+		if !slices.Contains(report.SortOrders, arg) {
+			return fmt.Errorf("unknown sort order %q, use one of %s", arg, strings.Join(report.SortOrders, ", "))
+		}
+		s.sort = arg
+		return nil
+	case "list", "peek":
+		if arg == "" {
+			return fmt.Errorf("%s needs a function name regexp", name)
+		}
+		match, err := regexp.Compile(arg)
+		if err != nil {
+			return fmt.Errorf("invalid regexp: %w", err)
+		}
+		if name == "list" {
+			return report.List(s.out, s.view.CallGraph(), match)
+		}
+		return report.Peek(s.out, s.view.CallGraph(), match)
+	case "tree":
+		opts := report.TreeOptions{MinFraction: s.options.NodeFraction}
+		if arg != "" {
+			depth, err := strconv.Atoi(arg)
+			if err != nil {
+				return fmt.Errorf("invalid depth: %s", arg)
+			}
+			opts.MaxDepth = depth
+		}
+		return report.Tree(s.out, s.view.CallGraph(), opts)
+	case "focus", "ignore", "hide", "show":
+		previous := s.exprs[name]
+		s.exprs[name] = arg
+		if err := s.applyFilter(); err != nil {
+			s.exprs[name] = previous
+			return err
+		}
+		s.printView()
+		return nil
+	case "reset":
+		for key := range s.exprs {
+			s.exprs[key] = ""
+		}
+		if err := s.applyFilter(); err != nil {
+			return err
+		}
+		s.printView()
+		return nil
+	case "nodecount":
+		return s.setCount(name, arg, &s.options.NodeCount)
+	case "nodefraction":
+		return s.setFraction(name, arg, &s.options.NodeFraction)
+	case "edgefraction":
+		return s.setFraction(name, arg, &s.options.EdgeFraction)
+	case "web":
+		return s.web()
+	case "svg":
+		return s.svg(arg)
+	}
+	return fmt.Errorf("unknown command %q, type help for the list of commands", name)
+}
+
+// top prints the top functions, arguments are an optional count and name regexp in any order
+func (s *session) top(arg string) error {
+	opts := report.TopOptions{Count: 10, Sort: s.sort}
+	for _, field := range strings.Fields(arg) {
+		if count, err := strconv.Atoi(field); err == nil {
+			opts.Count = count
+			continue
+		}
+		match, err := regexp.Compile(field)
+		if err != nil {
+			return fmt.Errorf("invalid regexp: %w", err)
+		}
+		opts.Match = match
+	}
+	return report.Top(s.out, s.view.CallGraph(), opts)
+}
+
+// setCount prints a count option, or sets it when a value is given
+func (s *session) setCount(name, arg string, option *int) error {
+	if arg == "" {
+		fmt.Fprintf(s.out, "%s = %d\n", name, *option)
+		return nil
+	}
+	value, err := strconv.Atoi(arg)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %s", name, arg)
+	}
+	*option = value
+	s.updateServer()
+	return nil
+}
+
+// setFraction prints a fraction option, or sets it when a value is given
+func (s *session) setFraction(name, arg string, option *float64) error {
+	if arg == "" {
+		fmt.Fprintf(s.out, "%s = %g\n", name, *option)
+		return nil
+	}
+	value, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %s", name, arg)
+	}
+	*option = value
+	s.updateServer()
+	return nil
+}
+
+// applyFilter rebuilds the current view from filter expressions
+func (s *session) applyFilter() error {
+	filter, err := spx.NewFilter(s.exprs["focus"], s.exprs["ignore"], s.exprs["hide"], s.exprs["show"])
+	if err != nil {
+		return err
+	}
+	view, err := s.base.WithFilter(filter)
+	if err != nil {
+		return err
+	}
+
+	s.view = view
+	s.updateServer()
+	return nil
+}
+
+// printView prints the active filter and what is left of the profile
+func (s *session) printView() {
+	filter := "none"
+	if !s.view.Filter().IsEmpty() {
+		filter = s.view.Filter().String()
+	}
+	fmt.Fprintf(s.out, "Filter: %s, %d functions left\n", filter, len(s.view.CallGraph().Nodes))
+}
+
+// generator returns a graph generator of the current view
+func (s *session) generator() *graph.Generator {
+	return s.view.WithOptions(s.options)
+}
+
+// updateServer makes a running web server show the current view
+func (s *session) updateServer() {
+	if s.server != nil {
+		s.server.SetGenerator(s.generator())
+	}
+}
+
+// web starts the web server on first use and opens the current view in a browser
+func (s *session) web() error {
+	url := fmt.Sprintf("http://localhost:%d", port)
+	if s.server == nil {
+		s.server = server.New(s.generator(), port)
+		go func() {
+			if err := s.server.Start(); err != nil {
+				fmt.Fprintf(os.Stderr, "\nServer error: %v\n", err)
+			}
+		}()
+	}
+
+	fmt.Fprintf(s.out, "Serving the current view at %s\n", url)
+	if err := openBrowser(url); err != nil {
+		fmt.Fprintf(s.out, "Open %s in a browser\n", url)
+	}
+	return nil
+}
+
+// svg saves the call graph of the current view
+func (s *session) svg(filename string) error {
+	if filename == "" {
+		filename = "profile.svg"
+	}
+
+	svg, err := s.generator().GenerateSVG()
+	if err != nil {
+		return fmt.Errorf("failed to generate graph: %w", err)
+	}
+	if err := os.WriteFile(filename, []byte(svg), 0644); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(s.out, "Saved graph to %s\n", filename)
+	return nil
+}
+
+// openBrowser opens url with the desktop default browser
+func openBrowser(url string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", url).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+	default:
+		return exec.Command("xdg-open", url).Start()
+	}
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/spf13/cobra"
+	"github.com/supercute/spx-graph/internal/report"
+)
+
+var peekCmd = &cobra.Command{
+	Use:   "peek <profile> <regexp>",
+	Short: "Print callers and callees of matching functions",
+	Long: `Print every function with a name matching the regexp, the callers it is called from
+and the callees it calls, like go tool pprof -peek. Times are inclusive times of the calls
+between the two functions, percentages are of total time.
+
+Examples:
+  spx-graph peek profile.txt.gz 'Repository::find'
+  spx-graph peek profile.txt.gz '^PDOStatement::execute$' --focus 'Controller::'`,
+	Args: cobra.ExactArgs(2),
+	RunE: runPeek,
+}
+
+func init() {
+	rootCmd.AddCommand(peekCmd)
+}
+
+func runPeek(cmd *cobra.Command, args []string) error {
+	match, err := regexp.Compile(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid function expression: %w", err)
+	}
+
+	filter, err := graphFilter()
+	if err != nil {
+		return err
+	}
+
+	_, callGraph, err := loadProfile(args[0])
+	if err != nil {
+		return err
+	}
+	if callGraph, err = callGraph.Filter(filter); err != nil {
+		return err
+	}
+
+	return report.Peek(os.Stdout, callGraph, match)
+}
@@ -6,13 +6,29 @@ import (
 	"github.com/supercute/spx-graph/internal/graph"
 	"github.com/supercute/spx-graph/internal/server"
 	"github.com/supercute/spx-graph/internal/spx"
+	"os"
 	"time"
 )
 
+// stdinFile is the --file value reading the profile from standard input
+const stdinFile = "-"
+
 var (
 	inputFile  string
+	inputDir   string
 	outputFile string
 	port       int
+
+	// graph pruning
+	nodeFraction float64
+	edgeFraction float64
+	nodeCount    int
+
+	// graph filters
+	focus  string
+	ignore string
+	hide   string
+	show   string
 )
 
 var rootCmd = &cobra.Command{
@@ -23,7 +39,9 @@ var rootCmd = &cobra.Command{
 Examples:
   spx-graph --file profile.txt.gz
   spx-graph --file profile.txt.gz -o result.html
-  spx-graph --file profile.txt.gz --port 9090`,
+  spx-graph --file profile.txt.gz --port 9090
+  ssh box cat profile.txt.gz | spx-graph --file -
+  spx-graph --dir /tmp/spx`,
 	RunE: runGraph,
 }
 
@@ -32,35 +50,110 @@ func Execute() error {
 }
 
 func init() {
-	rootCmd.Flags().StringVarP(&inputFile, "file", "f", "", "SPX profile file (.txt or .txt.gz)")
-	rootCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output HTML file (default: start server)")
-	rootCmd.Flags().IntVarP(&port, "port", "p", 8080, "Server port")
-	err := rootCmd.MarkFlagRequired("file")
-	if err != nil {
-		return
-	}
+	rootCmd.Flags().StringVarP(&inputFile, "file", "f", "", "SPX profile file (.txt or .txt.gz), - reads standard input")
+	rootCmd.Flags().StringVarP(&inputDir, "dir", "d", "", "SPX data directory to browse in the web server")
+	rootCmd.PersistentFlags().StringVarP(&outputFile, "output", "o", "", "Output HTML file (default: start server)")
+	rootCmd.PersistentFlags().IntVarP(&port, "port", "p", 8080, "Server port")
+
+	defaults := graph.DefaultOptions()
+	rootCmd.PersistentFlags().Float64Var(&nodeFraction, "nodefraction", defaults.NodeFraction, "Hide nodes below this fraction of total time")
+	rootCmd.PersistentFlags().Float64Var(&edgeFraction, "edgefraction", defaults.EdgeFraction, "Hide edges below this fraction of total time")
+	rootCmd.PersistentFlags().IntVar(&nodeCount, "nodecount", defaults.NodeCount, "Show at most this many nodes, 0 shows all")
+
+	rootCmd.PersistentFlags().StringVar(&focus, "focus", "", "Only show call paths through functions matching this regexp")
+	rootCmd.PersistentFlags().StringVar(&ignore, "ignore", "", "Drop call paths through functions matching this regexp")
+	rootCmd.PersistentFlags().StringVar(&hide, "hide", "", "Hide functions matching this regexp, their time goes to callers")
+	rootCmd.PersistentFlags().StringVar(&show, "show", "", "Only show functions matching this regexp, others are hidden")
+
+	rootCmd.MarkFlagsOneRequired("file", "dir")
+	rootCmd.MarkFlagsMutuallyExclusive("file", "dir")
+	rootCmd.MarkFlagsMutuallyExclusive("dir", "output")
 }
 
 func runGraph(cmd *cobra.Command, args []string) error {
-	// Parse spx file
-	profile, err := spx.ParseProfile(inputFile)
+	filter, err := graphFilter()
 	if err != nil {
-		return fmt.Errorf("failed to parse profile: %w", err)
+		return err
 	}
 
-	fmt.Printf("Parsed %d events, %d functions\n",
-		len(profile.Events), len(profile.Functions))
-
-	// Analyze call three
-	fmt.Printf("Analyze and build graph...\n")
-	callGraph := buildGraph(profile)
+	if inputDir != "" {
+		srv := server.NewDir(inputDir, port, graphOptions(), filter)
+		fmt.Printf("Browsing %s at http://localhost:%d\n", inputDir, port)
+		fmt.Println("Press Ctrl+C to stop")
+		return srv.Start()
+	}
 
-	fmt.Printf("Build call graph with %d nodes, %d edges\n",
-		len(callGraph.Nodes), len(callGraph.Edges))
+	profile, callGraph, err := loadProfile(inputFile)
+	if err != nil {
+		return err
+	}
 
 	// Generate graph
 	generator := graph.NewGenerator(callGraph, profile.Functions)
+	generator.SetMetadata(profile.Metadata)
+	generator.SetOptions(graphOptions())
+	if !filter.IsEmpty() {
+		if generator, err = generator.WithFilter(filter); err != nil {
+			return err
+		}
+	}
+
+	return output(generator)
+}
 
+// loadProfile streams a SPX profile file into a call graph, progress is reported on stderr
+func loadProfile(filename string) (*spx.Profile, *spx.CallGraph, error) {
+	start := time.Now()
+
+	// Parse spx file and analyze call three in one pass
+	fmt.Fprintf(os.Stderr, "Analyze and build graph...\n")
+	var profile *spx.Profile
+	var callGraph *spx.CallGraph
+	var err error
+	if filename == stdinFile {
+		profile, callGraph, err = spx.AnalyzeReader(os.Stdin, nil)
+	} else {
+		profile, callGraph, err = spx.AnalyzeFile(filename)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse profile: %w", err)
+	}
+
+	if profile.EventCount > 0 {
+		fmt.Fprintf(os.Stderr, "Parsed %d events, %d functions\n",
+			profile.EventCount, len(profile.Functions))
+	} else {
+		// Callgrind profiles have costs per function instead of events
+		fmt.Fprintf(os.Stderr, "Parsed %d functions\n", len(profile.Functions))
+	}
+	if profile.Metadata != nil {
+		fmt.Fprintf(os.Stderr, "Profile of %s\n", profile.Metadata.Target())
+	}
+
+	fmt.Fprintf(os.Stderr, "Build call graph with %d nodes, %d edges\n",
+		len(callGraph.Nodes), len(callGraph.Edges))
+	fmt.Fprintf(os.Stderr, "Build time is %v\n", time.Since(start))
+	printDiagnostics(callGraph.Diagnostics)
+
+	return profile, callGraph, nil
+}
+
+// graphOptions returns pruning options from flags
+func graphOptions() graph.Options {
+	return graph.Options{
+		NodeFraction: nodeFraction,
+		EdgeFraction: edgeFraction,
+		NodeCount:    nodeCount,
+	}
+}
+
+// graphFilter returns the call graph filter from flags
+func graphFilter() (*spx.Filter, error) {
+	return spx.NewFilter(focus, ignore, hide, show)
+}
+
+// output saves the report to --output or serves it on --port
+func output(generator *graph.Generator) error {
 	if outputFile != "" {
 		fmt.Printf("Saving HTML to: %s\n", outputFile)
 		return generator.SaveHTML(outputFile)
@@ -72,13 +165,28 @@ func runGraph(cmd *cobra.Command, args []string) error {
 	}
 }
 
-func buildGraph(profile *spx.Profile) *spx.CallGraph {
-	start := time.Now()
-	defer func() {
-		elapsed := time.Since(start)
-		fmt.Printf("Build time is %v\n", elapsed)
-	}()
-	analyzer := spx.NewAnalyzer(profile)
-	callGraph := analyzer.BuildCallGraph()
-	return callGraph
+// parseProfile parses a whole SPX profile file, keeping its events
+func parseProfile(filename string) (*spx.Profile, error) {
+	var profile *spx.Profile
+	var err error
+	if filename == stdinFile {
+		profile, err = spx.ParseReader(os.Stdin)
+	} else {
+		profile, err = spx.ParseProfile(filename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse profile: %w", err)
+	}
+	return profile, nil
+}
+
+// printDiagnostics reports events that did not form a well nested call tree
+func printDiagnostics(diagnostics *spx.Diagnostics) {
+	if diagnostics == nil || diagnostics.OK() {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %s\n", diagnostics)
+	for _, message := range diagnostics.Messages {
+		fmt.Fprintf(os.Stderr, "  %s\n", message)
+	}
 }
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/supercute/spx-graph/internal/report"
+)
+
+var (
+	topCount int
+	topSort  string
+	topMatch string
+)
+
+var topCmd = &cobra.Command{
+	Use:   "top <profile>",
+	Short: "Print the top functions by self or inclusive time",
+	Long: `Print a table of the most expensive functions, like go tool pprof -top.
+
+Columns:
+  flat    time spent in the function itself
+  flat%   flat time percentage of total time
+  sum%    running total of flat% in table order
+  cum     time spent in the function and its callees
+  cum%    cum time percentage of total time
+  calls   number of calls
+  memory  memory usage change of the calls
+
+Examples:
+  spx-graph top profile.txt.gz
+  spx-graph top profile.txt.gz -n 50 --sort cum
+  spx-graph top profile.txt.gz --match 'Doctrine\\'`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTop,
+}
+
+func init() {
+	topCmd.Flags().IntVarP(&topCount, "count", "n", 20, "Show at most this many functions, 0 shows all")
+	topCmd.Flags().StringVar(&topSort, "sort", report.SortFlat, "Sort by "+strings.Join(report.SortOrders, ", "))
+	topCmd.Flags().StringVar(&topMatch, "match", "", "Only show functions with names matching this regexp")
+	rootCmd.AddCommand(topCmd)
+}
+
+func runTop(cmd *cobra.Command, args []string) error {
+	opts := report.TopOptions{Count: topCount, Sort: topSort}
+	if topMatch != "" {
+		match, err := regexp.Compile(topMatch)
+		if err != nil {
+			return fmt.Errorf("invalid match expression: %w", err)
+		}
+		opts.Match = match
+	}
+
+	filter, err := graphFilter()
+	if err != nil {
+		return err
+	}
+
+	_, callGraph, err := loadProfile(args[0])
+	if err != nil {
+		return err
+	}
+	if callGraph, err = callGraph.Filter(filter); err != nil {
+		return err
+	}
+
+	return report.Top(os.Stdout, callGraph, opts)
+}
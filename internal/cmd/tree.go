@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/supercute/spx-graph/internal/report"
+)
+
+var treeDepth int
+
+var treeCmd = &cobra.Command{
+	Use:   "tree <profile>",
+	Short: "Print the call tree with every call path",
+	Long: `Print the calling context tree: unlike the call graph, a function called from two
+different call paths is shown twice, with the inclusive time (cum) and self time (flat)
+of its calls on each path. Call paths below --nodefraction of total time or deeper than
+--depth are summed up in one line.
+
+Examples:
+  spx-graph tree profile.txt.gz
+  spx-graph tree profile.txt.gz --depth 4 --nodefraction 0.05
+  spx-graph tree profile.txt.gz --focus 'Repository::find'`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTree,
+}
+
+func init() {
+	treeCmd.Flags().IntVar(&treeDepth, "depth", 0, "Show at most this many call levels, 0 shows all")
+	rootCmd.AddCommand(treeCmd)
+}
+
+func runTree(cmd *cobra.Command, args []string) error {
+	filter, err := graphFilter()
+	if err != nil {
+		return err
+	}
+
+	_, callGraph, err := loadProfile(args[0])
+	if err != nil {
+		return err
+	}
+	if callGraph, err = callGraph.Filter(filter); err != nil {
+		return err
+	}
+
+	return report.Tree(os.Stdout, callGraph, report.TreeOptions{MaxDepth: treeDepth, MinFraction: nodeFraction})
+}
@@ -0,0 +1,143 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/supercute/spx-graph/internal/spx"
+)
+
+// callgrindUnknownFile is the file of functions without source location, as shown by valgrind
+const callgrindUnknownFile = "???"
+
+// WriteCallgrind converts the SPX call graph to the callgrind format read by KCachegrind and QCachegrind.
+// Every recorded metric is an event type with exclusive costs per function and inclusive costs per call.
+// Costs are integers, negative self values like freed memory are written as 0.
+func WriteCallgrind(w io.Writer, p *spx.Profile) error {
+	bw := bufio.NewWriter(w)
+	callGraph := spx.NewAnalyzer(p).BuildCallGraph()
+
+	fmt.Fprintln(bw, "# callgrind format")
+	fmt.Fprintln(bw, "version: 1")
+	fmt.Fprintln(bw, "creator: spx-graph")
+	if p.Metadata != nil {
+		fmt.Fprintf(bw, "cmd: %s\n", p.Metadata.Target())
+		if p.Metadata.ProcessPID > 0 {
+			fmt.Fprintf(bw, "pid: %d\n", p.Metadata.ProcessPID)
+		}
+	}
+	fmt.Fprintln(bw, "positions: line")
+
+	keys := make([]string, len(p.Metrics))
+	for k, metric := range p.Metrics {
+		keys[k] = metric.Key
+		fmt.Fprintf(bw, "event: %s : %s (%s)\n", metric.Key, metric.Name, metric.Unit)
+	}
+	fmt.Fprintf(bw, "events: %s\n", strings.Join(keys, " "))
+
+	// Profile totals are the inclusive values of top level calls
+	totals := make([]float64, len(p.Metrics))
+	for _, child := range callGraph.Contexts.Children {
+		for k := range totals {
+			if k < len(child.Metrics) {
+				totals[k] += child.Metrics[k]
+			}
+		}
+	}
+	fmt.Fprintf(bw, "summary: %s\n\n", callgrindCosts(totals))
+
+	names := newCallgrindNames()
+
+	calls := make(map[int][]*spx.CallEdge)
+	for _, edge := range callGraph.Edges {
+		calls[edge.From] = append(calls[edge.From], edge)
+	}
+
+	// The synthetic root is left out, callgrind tools sum up top level calls themselves
+	ids := make([]int, 0, len(callGraph.Nodes))
+	for id := range callGraph.Nodes {
+		if id != spx.RootID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		node := callGraph.Nodes[id]
+		name := p.FunctionName(id)
+		file, line := callgrindLocation(name)
+
+		fmt.Fprintf(bw, "fl=%s\n", names.file(file))
+		fmt.Fprintf(bw, "fn=%s\n", names.function(name))
+		fmt.Fprintf(bw, "%d %s\n", line, callgrindCosts(node.SelfMetrics))
+
+		edges := calls[id]
+		sort.Slice(edges, func(i, j int) bool { return edges[i].To < edges[j].To })
+		for _, edge := range edges {
+			calleeName := p.FunctionName(edge.To)
+			calleeFile, calleeLine := callgrindLocation(calleeName)
+
+			fmt.Fprintf(bw, "cfl=%s\n", names.file(calleeFile))
+			fmt.Fprintf(bw, "cfn=%s\n", names.function(calleeName))
+			fmt.Fprintf(bw, "calls=%d %d\n", edge.CallCount, calleeLine)
+			fmt.Fprintf(bw, "%d %s\n", line, callgrindCosts(edge.Metrics))
+		}
+		fmt.Fprintln(bw)
+	}
+
+	return bw.Flush()
+}
+
+// callgrindLocation returns the source location of a function, with the valgrind placeholder for unknown files
+func callgrindLocation(name string) (string, int) {
+	file, line := sourceLocation(name)
+	if file == "" {
+		file = callgrindUnknownFile
+	}
+	return file, line
+}
+
+// callgrindCosts formats values as non negative integer costs
+func callgrindCosts(values []float64) string {
+	costs := make([]string, len(values))
+	for i, value := range values {
+		costs[i] = strconv.FormatInt(int64(math.Max(math.Round(value), 0)), 10)
+	}
+	return strings.Join(costs, " ")
+}
+
+// callgrindNames compresses repeated file and function names to "(id)" references
+type callgrindNames struct {
+	files     map[string]int
+	functions map[string]int
+}
+
+func newCallgrindNames() *callgrindNames {
+	return &callgrindNames{
+		files:     make(map[string]int),
+		functions: make(map[string]int),
+	}
+}
+
+func (n *callgrindNames) file(name string) string {
+	return compressName(n.files, name)
+}
+
+func (n *callgrindNames) function(name string) string {
+	return compressName(n.functions, name)
+}
+
+// compressName returns "(id) name" on first use of name and "(id)" afterwards
+func compressName(ids map[string]int, name string) string {
+	if id, ok := ids[name]; ok {
+		return fmt.Sprintf("(%d)", id)
+	}
+	id := len(ids) + 1
+	ids[name] = id
+	return fmt.Sprintf("(%d) %s", id, name)
+}
@@ -0,0 +1,60 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/supercute/spx-graph/internal/spx"
+)
+
+// CollapsedCalls is the collapsed stack value counting calls instead of a metric
+const CollapsedCalls = "calls"
+
+// WriteCollapsed writes every call path as a folded stack line "a;b;c value",
+// read by flamegraph.pl, difffolded.pl and inferno. The value is the self value
+// of the metric with key metric, or the call count for CollapsedCalls.
+// Paths with a value below 1, like calls freeing memory, are left out.
+func WriteCollapsed(w io.Writer, p *spx.Profile, metric string) error {
+	index := -1
+	if metric != CollapsedCalls {
+		index = p.MetricIndex(metric)
+		if index < 0 {
+			return fmt.Errorf("metric %s not recorded in profile", metric)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	callGraph := spx.NewAnalyzer(p).BuildCallGraph()
+
+	var stack []string
+	var walk func(n *spx.ContextNode)
+	walk = func(n *spx.ContextNode) {
+		stack = append(stack, collapsedName(p.FunctionName(n.FunctionID)))
+
+		value := float64(n.CallCount)
+		if index >= 0 {
+			value = n.SelfMetrics[index]
+		}
+		if value := math.Round(value); value >= 1 {
+			fmt.Fprintf(bw, "%s %d\n", strings.Join(stack, ";"), int64(value))
+		}
+
+		for _, child := range n.Children {
+			walk(child)
+		}
+		stack = stack[:len(stack)-1]
+	}
+	for _, child := range callGraph.Contexts.Children {
+		walk(child)
+	}
+
+	return bw.Flush()
+}
+
+// collapsedName replaces characters separating frames and values in folded stacks
+func collapsedName(name string) string {
+	return strings.NewReplacer(";", ":", "\n", " ").Replace(name)
+}
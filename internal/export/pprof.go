@@ -0,0 +1,99 @@
+package export
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/google/pprof/profile"
+	"github.com/supercute/spx-graph/internal/spx"
+)
+
+// WritePprof converts reconstructed SPX call stacks to a gzipped profile.proto.
+// Every call path becomes a sample with its self values: call count and each recorded metric.
+func WritePprof(w io.Writer, p *spx.Profile) error {
+	prof := &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "calls", Unit: "count"}},
+	}
+	for _, metric := range p.Metrics {
+		prof.SampleType = append(prof.SampleType, pprofValueType(metric))
+	}
+	if len(p.Metrics) > 0 {
+		prof.PeriodType = pprofValueType(p.Metrics[0])
+		prof.DefaultSampleType = prof.SampleType[1].Type
+	}
+	if p.Metadata != nil {
+		prof.TimeNanos = p.Metadata.ExecTime().UnixNano()
+		prof.DurationNanos = p.Metadata.WallTime().Nanoseconds()
+		prof.Comments = append(prof.Comments, p.Metadata.Target())
+	}
+
+	locations := make(map[int]*profile.Location)
+	location := func(funcID int) *profile.Location {
+		if loc, ok := locations[funcID]; ok {
+			return loc
+		}
+		name := p.FunctionName(funcID)
+		file, line := sourceLocation(name)
+		fn := &profile.Function{
+			ID:         uint64(len(prof.Function) + 1),
+			Name:       name,
+			SystemName: name,
+			Filename:   file,
+			StartLine:  int64(line),
+		}
+		loc := &profile.Location{
+			ID:   uint64(len(prof.Location) + 1),
+			Line: []profile.Line{{Function: fn, Line: int64(line)}},
+		}
+		prof.Function = append(prof.Function, fn)
+		prof.Location = append(prof.Location, loc)
+		locations[funcID] = loc
+		return loc
+	}
+
+	// Every call path of the calling context tree is one sample, its locations are leaf first
+	callGraph := spx.NewAnalyzer(p).BuildCallGraph()
+	var walk func(n *spx.ContextNode, stack []*profile.Location)
+	walk = func(n *spx.ContextNode, stack []*profile.Location) {
+		for _, child := range n.Children {
+			locs := make([]*profile.Location, 0, len(stack)+1)
+			locs = append(locs, location(child.FunctionID))
+			locs = append(locs, stack...)
+
+			sample := &profile.Sample{Location: locs, Value: make([]int64, len(p.Metrics)+1)}
+			sample.Value[0] = int64(child.CallCount)
+			for k, metric := range p.Metrics {
+				sample.Value[k+1] = pprofValue(metric, child.SelfMetrics[k])
+			}
+			prof.Sample = append(prof.Sample, sample)
+
+			walk(child, locs)
+		}
+	}
+	walk(callGraph.Contexts, nil)
+
+	if err := prof.CheckValid(); err != nil {
+		return fmt.Errorf("invalid pprof profile: %w", err)
+	}
+
+	return prof.Write(w)
+}
+
+// pprofValueType maps a SPX metric to a pprof sample type, times are exported in nanoseconds
+func pprofValueType(metric spx.Metric) *profile.ValueType {
+	switch metric.Unit {
+	case spx.UnitMicroseconds:
+		return &profile.ValueType{Type: metric.Key, Unit: "nanoseconds"}
+	case spx.UnitBytes:
+		return &profile.ValueType{Type: metric.Key, Unit: "bytes"}
+	default:
+		return &profile.ValueType{Type: metric.Key, Unit: "count"}
+	}
+}
+
+func pprofValue(metric spx.Metric, value float64) int64 {
+	if metric.Unit == spx.UnitMicroseconds {
+		value *= 1000
+	}
+	return int64(value)
+}
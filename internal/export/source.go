@@ -0,0 +1,23 @@
+package export
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// sourceLocationPattern matches names ending with a source location: "file.php:12" or "{closure}@file.php:12"
+var sourceLocationPattern = regexp.MustCompile(`^(?:(.*)@)?(.+\.(?:php|inc|phtml)):(\d+)$`)
+
+// sourceLocation parses file and line from a SPX function name, file is empty when unknown.
+// Included files are reported by their path, closures may carry their definition place.
+func sourceLocation(name string) (string, int) {
+	if m := sourceLocationPattern.FindStringSubmatch(name); m != nil {
+		line, _ := strconv.Atoi(m[3])
+		return m[2], line
+	}
+	if strings.HasPrefix(name, "/") {
+		return name, 0
+	}
+	return "", 0
+}
@@ -0,0 +1,154 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/supercute/spx-graph/internal/spx"
+)
+
+const speedscopeSchema = "https://www.speedscope.app/file-format-schema.json"
+
+type speedscopeFile struct {
+	Schema             string              `json:"$schema"`
+	Name               string              `json:"name"`
+	Exporter           string              `json:"exporter"`
+	ActiveProfileIndex int                 `json:"activeProfileIndex"`
+	Shared             speedscopeShared    `json:"shared"`
+	Profiles           []speedscopeProfile `json:"profiles"`
+}
+
+type speedscopeShared struct {
+	Frames []speedscopeFrame `json:"frames"`
+}
+
+type speedscopeFrame struct {
+	Name string `json:"name"`
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+}
+
+type speedscopeProfile struct {
+	Type       string            `json:"type"`
+	Name       string            `json:"name"`
+	Unit       string            `json:"unit"`
+	StartValue float64           `json:"startValue"`
+	EndValue   float64           `json:"endValue"`
+	Events     []speedscopeEvent `json:"events"`
+}
+
+type speedscopeEvent struct {
+	Type  string  `json:"type"` // O opens a frame, C closes it
+	Frame int     `json:"frame"`
+	At    float64 `json:"at"`
+}
+
+// WriteSpeedscope converts SPX events to a speedscope file with one evented profile per metric.
+// Speedscope needs values growing over time: metrics that can decrease, like memory usage,
+// are exported as the sum of their increases.
+func WriteSpeedscope(w io.Writer, p *spx.Profile) error {
+	file := &speedscopeFile{
+		Schema:   speedscopeSchema,
+		Name:     "SPX profile",
+		Exporter: "spx-graph",
+	}
+	if p.Metadata != nil {
+		file.Name = p.Metadata.Target()
+	}
+
+	frames := make(map[int]int)
+	frame := func(funcID int) int {
+		if index, ok := frames[funcID]; ok {
+			return index
+		}
+		name := p.FunctionName(funcID)
+		f := speedscopeFrame{Name: name}
+		f.File, f.Line = sourceLocation(name)
+		index := len(file.Shared.Frames)
+		file.Shared.Frames = append(file.Shared.Frames, f)
+		frames[funcID] = index
+		return index
+	}
+
+	// Calls are opened and closed like in the reconstructed call tree
+	tree := spx.NewAnalyzer(p).BuildCallTree()
+
+	for k, metric := range p.Metrics {
+		profile := speedscopeProfile{
+			Type: "evented",
+			Name: metric.Name,
+			Unit: speedscopeUnit(metric),
+		}
+
+		values := metricValues(p.Events, k)
+		if !isMonotonic(values) {
+			profile.Name += " (increases)"
+			values = increases(values)
+		}
+		if len(values) > 0 {
+			profile.StartValue = values[0]
+			profile.EndValue = values[len(values)-1]
+		}
+
+		var walk func(f *spx.Frame)
+		walk = func(f *spx.Frame) {
+			profile.Events = append(profile.Events, speedscopeEvent{Type: "O", Frame: frame(f.FunctionID), At: values[f.StartEvent]})
+			for _, child := range f.Children {
+				walk(child)
+			}
+			profile.Events = append(profile.Events, speedscopeEvent{Type: "C", Frame: frame(f.FunctionID), At: values[f.EndEvent]})
+		}
+		for _, root := range tree.Roots {
+			walk(root)
+		}
+
+		file.Profiles = append(file.Profiles, profile)
+	}
+
+	return json.NewEncoder(w).Encode(file)
+}
+
+// speedscopeUnit maps a SPX metric unit to a speedscope value unit
+func speedscopeUnit(metric spx.Metric) string {
+	switch metric.Unit {
+	case spx.UnitMicroseconds:
+		return "microseconds"
+	case spx.UnitBytes:
+		return "bytes"
+	default:
+		return "none"
+	}
+}
+
+// metricValues returns the values of metric k of every event
+func metricValues(events []spx.Event, k int) []float64 {
+	values := make([]float64, len(events))
+	for i := range events {
+		if k < len(events[i].Metrics) {
+			values[i] = events[i].Metrics[k]
+		}
+	}
+	return values
+}
+
+func isMonotonic(values []float64) bool {
+	for i := 1; i < len(values); i++ {
+		if values[i] < values[i-1] {
+			return false
+		}
+	}
+	return true
+}
+
+// increases returns the running sum of value increases, starting at the first value
+func increases(values []float64) []float64 {
+	result := make([]float64, len(values))
+	for i := range values {
+		if i == 0 {
+			result[i] = values[i]
+			continue
+		}
+		result[i] = result[i-1] + max(values[i]-values[i-1], 0)
+	}
+	return result
+}
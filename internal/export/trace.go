@@ -0,0 +1,139 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/supercute/spx-graph/internal/spx"
+)
+
+// traceEvent is an event of the Chrome Trace Event Format, read by chrome://tracing and Perfetto
+type traceEvent struct {
+	Name      string         `json:"name"`
+	Category  string         `json:"cat,omitempty"`
+	Phase     string         `json:"ph"`
+	Timestamp float64        `json:"ts"` // microseconds
+	Duration  *float64       `json:"dur,omitempty"`
+	PID       int            `json:"pid"`
+	TID       int            `json:"tid"`
+	Args      map[string]any `json:"args,omitempty"`
+}
+
+// WriteTrace converts SPX events to Trace Event Format JSON.
+// Every reconstructed call becomes a complete (X) event and memory metrics become counter (C) events.
+// Trace events are written one by one instead of building the whole JSON document.
+func WriteTrace(w io.Writer, p *spx.Profile) error {
+	bw := bufio.NewWriter(w)
+
+	pid, tid := 1, 1
+	processName := "php"
+	if p.Metadata != nil {
+		if p.Metadata.ProcessPID > 0 {
+			pid = p.Metadata.ProcessPID
+		}
+		if p.Metadata.ProcessTID > 0 {
+			tid = p.Metadata.ProcessTID
+		}
+		processName = p.Metadata.Target()
+	}
+
+	var start int64
+	if len(p.Events) > 0 {
+		start = p.Events[0].Time
+	}
+
+	fmt.Fprint(bw, `{"displayTimeUnit":"ms","traceEvents":[`)
+
+	count := 0
+	write := func(event *traceEvent) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			bw.WriteByte(',')
+		}
+		bw.WriteString("\n")
+		bw.Write(data)
+		count++
+		return nil
+	}
+
+	metadata := []*traceEvent{
+		{Name: "process_name", Phase: "M", PID: pid, TID: tid, Args: map[string]any{"name": processName}},
+		{Name: "thread_name", Phase: "M", PID: pid, TID: tid, Args: map[string]any{"name": "main"}},
+	}
+	for _, event := range metadata {
+		if err := write(event); err != nil {
+			return err
+		}
+	}
+
+	// Calls in enter order
+	tree := spx.NewAnalyzer(p).BuildCallTree()
+	for _, frame := range tree.Frames {
+		duration := float64(frame.EndTime - frame.StartTime)
+		event := &traceEvent{
+			Name:      p.FunctionName(frame.FunctionID),
+			Category:  "php",
+			Phase:     "X",
+			Timestamp: float64(frame.StartTime - start),
+			Duration:  &duration,
+			PID:       pid,
+			TID:       tid,
+		}
+		if frame.Truncated {
+			event.Args = map[string]any{"truncated": true}
+		}
+		if err := write(event); err != nil {
+			return err
+		}
+	}
+
+	// Memory counters, written only when a value changes
+	var memory []int
+	for k, metric := range p.Metrics {
+		if metric.Unit == spx.UnitBytes {
+			memory = append(memory, k)
+		}
+	}
+	if len(memory) > 0 {
+		last := make([]float64, len(p.Metrics))
+		for i := range p.Events {
+			event := &p.Events[i]
+
+			changed := i == 0
+			args := make(map[string]any, len(memory))
+			for _, k := range memory {
+				if k >= len(event.Metrics) {
+					continue
+				}
+				args[p.Metrics[k].Name] = event.Metrics[k]
+				if event.Metrics[k] != last[k] {
+					changed = true
+					last[k] = event.Metrics[k]
+				}
+			}
+			if !changed {
+				continue
+			}
+
+			counter := &traceEvent{
+				Name:      "memory",
+				Phase:     "C",
+				Timestamp: float64(event.Time - start),
+				PID:       pid,
+				TID:       tid,
+				Args:      args,
+			}
+			if err := write(counter); err != nil {
+				return err
+			}
+		}
+	}
+
+	fmt.Fprint(bw, "\n]}\n")
+	return bw.Flush()
+}
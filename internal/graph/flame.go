@@ -0,0 +1,55 @@
+package graph
+
+import (
+	"github.com/supercute/spx-graph/internal/spx"
+)
+
+// flameMinFraction is the fraction of total time below which call paths are not drawn
+const flameMinFraction = 0.0001
+
+// flameNode is a call path of the flame graph, encoded with short keys to keep the HTML small
+type flameNode struct {
+	Name     string       `json:"n"`
+	Value    int64        `json:"v"` // inclusive time in nanoseconds
+	Self     int64        `json:"s"` // exclusive time in nanoseconds
+	Calls    int          `json:"c"`
+	Children []*flameNode `json:"ch,omitempty"`
+	Total    int64        `json:"t,omitempty"` // percentage baseline in nanoseconds, set on the root only
+}
+
+// flameGraph converts the calling context tree of the call graph, nil when call paths are unknown
+func (g *Generator) flameGraph() *flameNode {
+	contexts := g.callGraph.Contexts
+	if contexts == nil || len(contexts.Children) == 0 {
+		return nil
+	}
+
+	root := &flameNode{Name: "all", Total: int64(g.callGraph.Total)}
+	for _, child := range contexts.Children {
+		root.Value += max(int64(child.Duration), 0)
+	}
+
+	minValue := int64(float64(root.Value) * flameMinFraction)
+	root.Children = g.flameChildren(contexts, minValue)
+
+	return root
+}
+
+func (g *Generator) flameChildren(n *spx.ContextNode, minValue int64) []*flameNode {
+	var children []*flameNode
+	for _, child := range n.Children {
+		value := int64(child.Duration)
+		if value <= 0 || value < minValue {
+			continue
+		}
+
+		children = append(children, &flameNode{
+			Name:     g.callGraph.FunctionName(child.FunctionID),
+			Value:    value,
+			Self:     max(int64(child.SelfDuration), 0),
+			Calls:    child.CallCount,
+			Children: g.flameChildren(child, minValue),
+		})
+	}
+	return children
+}
@@ -17,15 +17,64 @@ import (
 type Generator struct {
 	callGraph *spx.CallGraph
 	functions map[int]string
+	metadata  *spx.Metadata
+	options   Options
+	filter    *spx.Filter
 }
 
 func NewGenerator(callGraph *spx.CallGraph, functions map[int]string) *Generator {
 	return &Generator{
 		callGraph: callGraph,
 		functions: functions,
+		options:   DefaultOptions(),
 	}
 }
 
+// SetMetadata sets the SPX run metadata shown in the HTML header
+func (g *Generator) SetMetadata(metadata *spx.Metadata) {
+	g.metadata = metadata
+}
+
+// SetOptions sets node and edge pruning options
+func (g *Generator) SetOptions(options Options) {
+	g.options = options
+}
+
+// Options returns node and edge pruning options
+func (g *Generator) Options() Options {
+	return g.options
+}
+
+// WithOptions returns a copy of the generator using other pruning options
+func (g *Generator) WithOptions(options Options) *Generator {
+	copied := *g
+	copied.options = options
+	return &copied
+}
+
+// CallGraph returns the call graph drawn by the generator
+func (g *Generator) CallGraph() *spx.CallGraph {
+	return g.callGraph
+}
+
+// Filter returns the focus, ignore, hide and show filter of the call graph
+func (g *Generator) Filter() *spx.Filter {
+	return g.filter
+}
+
+// WithFilter returns a copy of the generator drawing the call graph restricted by filter
+func (g *Generator) WithFilter(filter *spx.Filter) (*Generator, error) {
+	callGraph, err := g.callGraph.Filter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	copied := *g
+	copied.callGraph = callGraph
+	copied.filter = filter
+	return &copied, nil
+}
+
 func (g *Generator) GenerateSVG() (string, error) {
 	ctx := context.Background()
 
@@ -55,23 +104,17 @@ func (g *Generator) GenerateSVG() (string, error) {
 	// Find max percentage for normalization
 	maxPercentage := 0.0
 	maxSelfPercentage := 0.0
+	// Diff graphs carry signed values, so magnitudes are compared
 	for _, node := range g.callGraph.Nodes {
-		if node.Percentage > maxPercentage {
-			maxPercentage = node.Percentage
-		}
-		if node.SelfPercentage > maxSelfPercentage {
-			maxSelfPercentage = node.SelfPercentage
-		}
+		maxPercentage = math.Max(maxPercentage, math.Abs(node.Percentage))
+		maxSelfPercentage = math.Max(maxSelfPercentage, math.Abs(node.SelfPercentage))
 	}
 
+	sel := g.prune()
+
 	// Create nodes
 	nodeMap := make(map[int]*graphviz.Node)
-	for id, node := range g.callGraph.Nodes {
-		// Skipping nodes with very low time
-		if node.Percentage < 0.1 {
-			continue
-		}
-
+	for id, node := range sel.nodes {
 		nodeName := fmt.Sprintf("n%d", id)
 		n, err := graph.CreateNodeByName(nodeName)
 		if err != nil {
@@ -86,6 +129,7 @@ func (g *Generator) GenerateSVG() (string, error) {
 
 		// Set attributes
 		n.SetLabel(label)
+		n.SetTooltip(g.createNodeTooltip(node))
 		n.SetShape("box")
 		n.SetStyle("filled")
 		n.SetFillColor(nodeColor)
@@ -99,12 +143,10 @@ func (g *Generator) GenerateSVG() (string, error) {
 	// Create adges
 	maxEdgePercentage := 0.0
 	for _, edge := range g.callGraph.Edges {
-		if edge.Percentage > maxEdgePercentage {
-			maxEdgePercentage = edge.Percentage
-		}
+		maxEdgePercentage = math.Max(maxEdgePercentage, math.Abs(edge.Percentage))
 	}
 
-	for _, edge := range g.callGraph.Edges {
+	for _, edge := range sel.edges {
 		fromNode := nodeMap[edge.From]
 		toNode := nodeMap[edge.To]
 
@@ -112,11 +154,6 @@ func (g *Generator) GenerateSVG() (string, error) {
 			continue
 		}
 
-		// Skipping low edges
-		if edge.Percentage < 0.1 {
-			continue
-		}
-
 		edgeName := fmt.Sprintf("e_%d_%d", edge.From, edge.To)
 		e, err := graph.CreateEdgeByName(edgeName, fromNode, toNode)
 		if err != nil {
@@ -129,8 +166,9 @@ func (g *Generator) GenerateSVG() (string, error) {
 
 		e.SetPenWidth(edgeWidth)
 		e.SetColor(edgeColor)
-		e.SetLabel(fmt.Sprintf("%.1f%%\\n%d calls", edge.Percentage, edge.CallCount))
+		e.SetLabel(g.createEdgeLabel(edge))
 		e.SetFontSize(8.0)
+		e.SetTooltip(g.createEdgeTooltip(edge))
 	}
 
 	var buf bytes.Buffer
@@ -161,6 +199,14 @@ func (g *Generator) formatFunctionName(name string) string {
 func (g *Generator) createNodeLabel(node *spx.CallNode) string {
 	name := g.formatFunctionName(node.Name)
 
+	if g.callGraph.Diff {
+		return fmt.Sprintf("%s\n%+.1f%% (%+.1f%%)\n%s",
+			name,
+			node.SelfPercentage,
+			node.Percentage,
+			g.formatSignedDuration(node.SelfDuration))
+	}
+
 	return fmt.Sprintf("%s\n%.1f%% (%.1f%%)\n%s",
 		name,
 		node.SelfPercentage,
@@ -168,7 +214,88 @@ func (g *Generator) createNodeLabel(node *spx.CallNode) string {
 		g.formatDuration(node.SelfDuration))
 }
 
+func (g *Generator) createEdgeLabel(edge *spx.CallEdge) string {
+	if g.callGraph.Diff {
+		return fmt.Sprintf("%+.1f%%\\n%+d calls", edge.Percentage, edge.CallCount)
+	}
+	return fmt.Sprintf("%.1f%%\\n%d calls", edge.Percentage, edge.CallCount)
+}
+
+// createNodeTooltip lists every recorded metric of the node
+func (g *Generator) createNodeTooltip(node *spx.CallNode) string {
+	var sb strings.Builder
+	sb.WriteString(node.Name)
+	fmt.Fprintf(&sb, "\nCalls: %d", node.CallCount)
+	for i, metric := range g.callGraph.Metrics {
+		if i >= len(node.Metrics) || i >= len(node.SelfMetrics) {
+			break
+		}
+		fmt.Fprintf(&sb, "\n%s: %s (self %s)",
+			metric.Name,
+			g.formatMetricValue(metric, node.Metrics[i]),
+			g.formatMetricValue(metric, node.SelfMetrics[i]))
+	}
+	return sb.String()
+}
+
+// createEdgeTooltip lists every recorded metric of the edge
+func (g *Generator) createEdgeTooltip(edge *spx.CallEdge) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Calls: %d", edge.CallCount)
+	for i, metric := range g.callGraph.Metrics {
+		if i >= len(edge.Metrics) {
+			break
+		}
+		fmt.Fprintf(&sb, "\n%s: %s", metric.Name, g.formatMetricValue(metric, edge.Metrics[i]))
+	}
+	return sb.String()
+}
+
+func (g *Generator) formatMetricValue(metric spx.Metric, value float64) string {
+	switch metric.Unit {
+	case spx.UnitMicroseconds:
+		return g.formatDuration(time.Duration(value * float64(time.Microsecond)))
+	case spx.UnitBytes:
+		return g.formatBytes(value)
+	default:
+		return fmt.Sprintf("%.0f", value)
+	}
+}
+
+func (g *Generator) formatBytes(b float64) string {
+	return FormatBytes(b)
+}
+
 func (g *Generator) formatDuration(d time.Duration) string {
+	return FormatDuration(d)
+}
+
+func (g *Generator) formatSignedDuration(d time.Duration) string {
+	if d >= 0 {
+		return "+" + FormatDuration(d)
+	}
+	return FormatDuration(d)
+}
+
+// FormatBytes formats a byte count with a binary unit suffix
+func FormatBytes(b float64) string {
+	abs := math.Abs(b)
+	if abs < 1024 {
+		return fmt.Sprintf("%.0fB", b)
+	} else if abs < 1024*1024 {
+		return fmt.Sprintf("%.1fKB", b/1024)
+	} else if abs < 1024*1024*1024 {
+		return fmt.Sprintf("%.1fMB", b/(1024*1024))
+	} else {
+		return fmt.Sprintf("%.2fGB", b/(1024*1024*1024))
+	}
+}
+
+// FormatDuration formats a duration with μs, ms or s precision
+func FormatDuration(d time.Duration) string {
+	if d < 0 {
+		return "-" + FormatDuration(-d)
+	}
 	if d < time.Millisecond {
 		return fmt.Sprintf("%.0fμs", float64(d.Nanoseconds())/1000)
 	} else if d < time.Second {
@@ -186,7 +313,7 @@ func (g *Generator) calculateNodeSize(node *spx.CallNode, maxPercentage float64)
 		return minSize
 	}
 
-	ratio := node.SelfPercentage / maxPercentage
+	ratio := math.Abs(node.SelfPercentage) / maxPercentage
 	return minSize + (maxSize-minSize)*ratio
 }
 
@@ -202,7 +329,7 @@ func (g *Generator) calculateEdgeWidth(edge *spx.CallEdge, maxPercentage float64
 		return minWidth
 	}
 
-	ratio := edge.Percentage / maxPercentage
+	ratio := math.Abs(edge.Percentage) / maxPercentage
 	return minWidth + (maxWidth-minWidth)*ratio
 }
 
@@ -299,14 +426,79 @@ func (g *Generator) generateHTML(svg string) string {
 		NodeCount  int
 		EdgeCount  int
 		TotalCalls int
+		Metadata   *metadataView
+		Diff       bool
+		Warning    string
+		Filter     string
+		Options    Options
+		Elided     bool
+		ShownNodes int
+		ShownEdges int
+		Flame      *flameNode
+		Timeline   *timelineData
+		Peek       *peekData
 	}{
 		SVG:        template.HTML(svg),
 		NodeCount:  nodeCount,
 		EdgeCount:  edgeCount,
 		TotalCalls: totalCalls,
+		Metadata:   g.metadataView(),
+		Diff:       g.callGraph.Diff,
+		Filter:     g.filter.String(),
+		Flame:      g.flameGraph(),
+		Timeline:   g.timeline(),
+		Peek:       g.peek(),
+	}
+
+	sel := g.prune()
+	data.Options = g.options
+	data.Elided = sel.elidedNodes > 0 || sel.elidedEdges > 0
+	data.ShownNodes = len(sel.nodes)
+	data.ShownEdges = len(sel.edges)
+	if d := g.callGraph.Diagnostics; d != nil && !d.OK() {
+		data.Warning = d.String()
 	}
 
 	var buf bytes.Buffer
 	t.Execute(&buf, data)
 	return buf.String()
 }
+
+// metadataView is the SPX run metadata formatted for the HTML header
+type metadataView struct {
+	Target     string
+	Host       string
+	PHPVersion string
+	ExecTime   string
+	WallTime   string
+	PeakMemory string
+	Metrics    string
+}
+
+func (g *Generator) metadataView() *metadataView {
+	if g.metadata == nil {
+		return nil
+	}
+
+	metrics := make([]string, 0, len(g.callGraph.Metrics))
+	for _, metric := range g.callGraph.Metrics {
+		metrics = append(metrics, metric.Name)
+	}
+
+	view := &metadataView{
+		Target:     g.metadata.Target(),
+		Host:       g.metadata.HTTPHost,
+		PHPVersion: g.metadata.PHPVersion,
+		WallTime:   g.formatDuration(g.metadata.WallTime()),
+		PeakMemory: g.formatBytes(float64(g.metadata.PeakMemoryUsage)),
+		Metrics:    strings.Join(metrics, ", "),
+	}
+	if view.Host == "" {
+		view.Host = g.metadata.HostName
+	}
+	if g.metadata.ExecTs > 0 {
+		view.ExecTime = g.metadata.ExecTime().Format("2006-01-02 15:04:05")
+	}
+
+	return view
+}
@@ -0,0 +1,77 @@
+package graph
+
+import (
+	"math"
+	"sort"
+
+	"github.com/supercute/spx-graph/internal/spx"
+)
+
+// Options controls which nodes and edges are drawn, like pprof flags of the same names
+type Options struct {
+	NodeFraction float64 // hide nodes below this fraction of total time
+	EdgeFraction float64 // hide edges below this fraction of total time
+	NodeCount    int     // show at most this many nodes, 0 shows all
+}
+
+// DefaultOptions returns the default pruning options
+func DefaultOptions() Options {
+	return Options{
+		NodeFraction: 0.001,
+		EdgeFraction: 0.001,
+		NodeCount:    80,
+	}
+}
+
+// selection is the part of the call graph that is drawn
+type selection struct {
+	nodes       map[int]*spx.CallNode
+	edges       []*spx.CallEdge
+	elidedNodes int
+	elidedEdges int
+}
+
+// prune selects nodes and edges to draw according to options
+func (g *Generator) prune() *selection {
+	opts := g.options
+
+	var nodes []*spx.CallNode
+	for _, node := range g.callGraph.Nodes {
+		// Diff graphs carry signed values, so magnitudes are compared
+		if math.Abs(node.Percentage) >= opts.NodeFraction*100 {
+			nodes = append(nodes, node)
+		}
+	}
+
+	// Keep the heaviest nodes by inclusive time
+	if opts.NodeCount > 0 && len(nodes) > opts.NodeCount {
+		sort.Slice(nodes, func(i, j int) bool {
+			pi, pj := math.Abs(nodes[i].Percentage), math.Abs(nodes[j].Percentage)
+			if pi != pj {
+				return pi > pj
+			}
+			return nodes[i].FunctionID < nodes[j].FunctionID
+		})
+		nodes = nodes[:opts.NodeCount]
+	}
+
+	sel := &selection{nodes: make(map[int]*spx.CallNode, len(nodes))}
+	for _, node := range nodes {
+		sel.nodes[node.FunctionID] = node
+	}
+
+	for _, edge := range g.callGraph.Edges {
+		if sel.nodes[edge.From] == nil || sel.nodes[edge.To] == nil {
+			continue
+		}
+		if math.Abs(edge.Percentage) < opts.EdgeFraction*100 {
+			continue
+		}
+		sel.edges = append(sel.edges, edge)
+	}
+
+	sel.elidedNodes = len(g.callGraph.Nodes) - len(sel.nodes)
+	sel.elidedEdges = len(g.callGraph.Edges) - len(sel.edges)
+
+	return sel
+}
@@ -0,0 +1,68 @@
+package graph
+
+import (
+	"sort"
+)
+
+// peekData is the callers and callees of every function for the HTML side panel
+type peekData struct {
+	Total     int64                 `json:"total"` // percentage baseline in nanoseconds
+	Functions map[int]*peekFunction `json:"functions"`
+}
+
+// peekFunction is a function with its callers and callees for the HTML side panel
+type peekFunction struct {
+	Name    string     `json:"n"`
+	Total   int64      `json:"v"` // inclusive time in nanoseconds
+	Self    int64      `json:"s"` // exclusive time in nanoseconds
+	Calls   int        `json:"c"`
+	Callers []peekCall `json:"in,omitempty"`
+	Callees []peekCall `json:"out,omitempty"`
+}
+
+// peekCall is the inclusive time of the calls between two functions
+type peekCall struct {
+	ID    int   `json:"id"`
+	Value int64 `json:"v"` // nanoseconds
+	Calls int   `json:"c"`
+}
+
+// peek returns every function of the call graph by ID with its callers and callees,
+// ordered by decreasing time. Functions pruned from the graph are kept so they can be reached from the panel.
+func (g *Generator) peek() *peekData {
+	functions := make(map[int]*peekFunction, len(g.callGraph.Nodes))
+	for id, node := range g.callGraph.Nodes {
+		functions[id] = &peekFunction{
+			Name:  g.callGraph.FunctionName(id),
+			Total: int64(node.TotalDuration),
+			Self:  int64(node.SelfDuration),
+			Calls: node.CallCount,
+		}
+	}
+
+	for _, edge := range g.callGraph.Edges {
+		from, to := functions[edge.From], functions[edge.To]
+		if from == nil || to == nil {
+			continue
+		}
+		to.Callers = append(to.Callers, peekCall{ID: edge.From, Value: int64(edge.TotalDuration), Calls: edge.CallCount})
+		from.Callees = append(from.Callees, peekCall{ID: edge.To, Value: int64(edge.TotalDuration), Calls: edge.CallCount})
+	}
+
+	for _, function := range functions {
+		sortPeekCalls(function.Callers, functions)
+		sortPeekCalls(function.Callees, functions)
+	}
+
+	return &peekData{Total: int64(g.callGraph.Total), Functions: functions}
+}
+
+// sortPeekCalls orders calls by decreasing time, then by function name
+func sortPeekCalls(calls []peekCall, functions map[int]*peekFunction) {
+	sort.Slice(calls, func(i, j int) bool {
+		if calls[i].Value != calls[j].Value {
+			return calls[i].Value > calls[j].Value
+		}
+		return functions[calls[i].ID].Name < functions[calls[j].ID].Name
+	})
+}
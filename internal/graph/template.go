@@ -39,6 +39,14 @@ func (g *Generator) getHTMLTemplate() string {
                 margin-top: 4px;
              }
              
+             .header .warning {
+                color: #b45309;
+             }
+             
+             .header .metadata span + span::before {
+                content: " · ";
+             }
+             
              .controls {
                 background: #ffffff;
                 border-bottom: 1px solid #e1e5e9;
@@ -119,12 +127,241 @@ func (g *Generator) getHTMLTemplate() string {
                 max-width: none;
                 height: auto;
              }
+             
+             .view-controls {
+                display: flex;
+                gap: 8px;
+             }
+             
+             .btn.active {
+                background: #e5e7eb;
+                font-weight: 600;
+             }
+             
+             .flame-controls {
+                display: none;
+                gap: 8px;
+                align-items: center;
+                margin-left: auto;
+                font-size: 14px;
+             }
+             
+             .flame-controls input {
+                border: 1px solid #d1d5db;
+                padding: 6px 8px;
+                font-size: 14px;
+                width: 240px;
+             }
+             
+             .flame-container {
+                display: none;
+                position: relative;
+                background: #ffffff;
+                height: calc(100vh - 140px);
+                overflow: auto;
+                border-top: 1px solid #e1e5e9;
+             }
+             
+             .flame-frame {
+                position: absolute;
+                height: 17px;
+                padding: 0 3px;
+                font-size: 12px;
+                line-height: 17px;
+                color: #1a1a1a;
+                white-space: nowrap;
+                overflow: hidden;
+                border-right: 1px solid #ffffff;
+                cursor: pointer;
+             }
+             
+             .flame-frame:hover {
+                filter: brightness(0.9);
+             }
+             
+             .flame-frame.matched {
+                background: #d946ef !important;
+             }
+             
+             .timeline-controls {
+                display: none;
+                gap: 8px;
+                align-items: center;
+                margin-left: auto;
+                font-size: 14px;
+                color: #6c757d;
+             }
+             
+             .timeline-container {
+                display: none;
+                position: relative;
+                background: #ffffff;
+                height: calc(100vh - 140px);
+                overflow-x: hidden;
+                overflow-y: auto;
+                border-top: 1px solid #e1e5e9;
+                cursor: grab;
+             }
+             
+             .tree-container {
+                display: none;
+                background: #ffffff;
+                height: calc(100vh - 140px);
+                overflow: auto;
+                border-top: 1px solid #e1e5e9;
+             }
+             
+             .tree-table {
+                border-collapse: collapse;
+                font-size: 13px;
+                min-width: 100%;
+             }
+             
+             .tree-table th {
+                position: sticky;
+                top: 0;
+                background: #f8f9fa;
+                border-bottom: 1px solid #e1e5e9;
+                padding: 6px 8px;
+                font-weight: 600;
+                text-align: right;
+             }
+             
+             .tree-table td {
+                padding: 2px 8px;
+                border-bottom: 1px solid #f1f3f5;
+                text-align: right;
+                white-space: nowrap;
+             }
+             
+             .tree-table th.name,
+             .tree-table td.name {
+                text-align: left;
+                width: 100%;
+             }
+             
+             .tree-table tr:hover td {
+                background: #f3f4f6;
+             }
+             
+             .tree-toggle {
+                display: inline-block;
+                width: 16px;
+                color: #6c757d;
+                cursor: pointer;
+             }
+             
+             .tree-bar {
+                display: inline-block;
+                height: 8px;
+                margin-right: 6px;
+                background: #f97316;
+                vertical-align: middle;
+             }
+             
+             .peek-panel {
+                display: none;
+                position: absolute;
+                top: 0;
+                right: 0;
+                bottom: 0;
+                width: 420px;
+                background: #ffffff;
+                border-left: 1px solid #e1e5e9;
+                padding: 12px 16px;
+                overflow-y: auto;
+                font-size: 13px;
+             }
+             
+             .peek-panel h2 {
+                font-size: 15px;
+                font-weight: 600;
+                word-break: break-all;
+                padding-right: 24px;
+             }
+             
+             .peek-panel h3 {
+                font-size: 13px;
+                font-weight: 600;
+                margin: 12px 0 4px;
+             }
+             
+             .peek-panel p {
+                color: #6c757d;
+                margin-top: 4px;
+             }
+             
+             .peek-panel table {
+                width: 100%;
+                border-collapse: collapse;
+             }
+             
+             .peek-panel td {
+                padding: 3px 4px;
+                border-bottom: 1px solid #f1f3f5;
+                white-space: nowrap;
+                text-align: right;
+             }
+             
+             .peek-panel td.name {
+                text-align: left;
+                white-space: normal;
+                word-break: break-all;
+                color: #1d4ed8;
+                cursor: pointer;
+             }
+             
+             .peek-close {
+                position: absolute;
+                top: 8px;
+                right: 12px;
+                border: none;
+                background: none;
+                font-size: 18px;
+                cursor: pointer;
+             }
+             
+             .node {
+                cursor: pointer;
+             }
+             
+             .node.selected polygon {
+                stroke: #d946ef;
+                stroke-width: 3px;
+             }
+             
+             .timeline-tooltip {
+                display: none;
+                position: fixed;
+                background: #1a1a1a;
+                color: #ffffff;
+                font-size: 12px;
+                padding: 6px 8px;
+                pointer-events: none;
+                white-space: pre;
+                z-index: 10;
+             }
           </style>
        </head>
        <body>
           <div class="header">
-             <h1>SPX Profile Graph</h1>
+             <h1>{{if .Diff}}SPX Profile Diff{{else}}SPX Profile Graph{{end}}</h1>
+             {{with .Metadata}}
+             <p class="metadata">
+                <span><strong>{{.Target}}</strong></span>
+                {{if .Host}}<span>{{.Host}}</span>{{end}}
+                {{if .ExecTime}}<span>{{.ExecTime}}</span>{{end}}
+                {{if .PHPVersion}}<span>PHP {{.PHPVersion}}</span>{{end}}
+                <span>Wall time {{.WallTime}}</span>
+                <span>Peak memory {{.PeakMemory}}</span>
+             </p>
+             <p>Metrics: {{.Metrics}}</p>
+             {{else}}
              <p>Call graph with profiling data</p>
+             {{end}}
+             {{if .Diff}}<p>Values are new minus base profile: red got slower, green got faster</p>{{end}}
+             {{if .Filter}}<p>Filter: {{.Filter}}</p>{{end}}
+             {{if .Warning}}<p class="warning">Warning: {{.Warning}}</p>{{end}}
           </div>
           
           <div class="controls">
@@ -138,9 +375,51 @@ func (g *Generator) getHTMLTemplate() string {
                 <div class="stat-item">
                    <span class="stat-value">{{.TotalCalls}}</span> Total Calls
                 </div>
+                {{if .Elided}}
+                <div class="stat-item" title="nodefraction={{.Options.NodeFraction}} edgefraction={{.Options.EdgeFraction}} nodecount={{.Options.NodeCount}}">
+                   Showing <span class="stat-value">{{.ShownNodes}}</span> of {{.NodeCount}} nodes,
+                   <span class="stat-value">{{.ShownEdges}}</span> of {{.EdgeCount}} edges
+                </div>
+                {{end}}
+             </div>
+             
+             {{if or .Flame .Timeline}}
+             <div class="view-controls">
+                <button class="btn active" id="view-graph" onclick="showView('graph')">Call Graph</button>
+                {{if .Flame}}
+                <button class="btn" id="view-flame" onclick="showView('flame')">Flame Graph</button>
+                <button class="btn" id="view-icicle" onclick="showView('icicle')">Icicle</button>
+                <button class="btn" id="view-tree" onclick="showView('tree')">Call Tree</button>
+                {{end}}
+                {{if .Timeline}}
+                <button class="btn" id="view-timeline" onclick="showView('timeline')">Timeline</button>
+                {{end}}
+             </div>
+             {{end}}
+             
+             {{if .Flame}}
+             <div class="flame-controls" id="flame-controls">
+                <input type="search" id="flame-search" placeholder="Search functions (regexp)" oninput="searchFlame(this.value)">
+                <span id="flame-matched"></span>
+                <button class="btn" onclick="zoomFlame(null)">Reset Zoom</button>
+             </div>
+             {{end}}
+             
+             {{if .Flame}}
+             <div class="flame-controls" id="tree-controls">
+                <button class="btn" onclick="expandTree(true)">Expand Hot Paths</button>
+                <button class="btn" onclick="expandTree(false)">Collapse All</button>
+             </div>
+             {{end}}
+             
+             {{if .Timeline}}
+             <div class="timeline-controls" id="timeline-controls">
+                <span id="timeline-info"></span>
+                <button class="btn" onclick="resetTimeline()">Reset Zoom</button>
              </div>
+             {{end}}
              
-             <div class="zoom-controls">
+             <div class="zoom-controls" id="zoom-controls">
                 <button class="btn" onclick="zoomIn()">Zoom In</button>
                 <button class="btn" onclick="zoomOut()">Zoom Out</button>
                 <button class="btn" onclick="resetZoom()">Reset</button>
@@ -148,13 +427,30 @@ func (g *Generator) getHTMLTemplate() string {
              </div>
           </div>
           
-          <div class="graph-container">
+          <div class="graph-container" id="graph-view">
              <div class="graph-viewport" id="viewport">
                 <div class="graph-content" id="content">
                    {{.SVG}}
                 </div>
              </div>
+             <div class="peek-panel" id="peek-panel"></div>
+          </div>
+          
+          <div class="flame-container" id="flame-view"></div>
+          
+          <div class="tree-container" id="tree-view">
+             <table class="tree-table">
+                <thead>
+                   <tr><th class="name">Call path</th><th>cum</th><th>cum%</th><th>flat</th><th>flat%</th><th>calls</th></tr>
+                </thead>
+                <tbody id="tree-body"></tbody>
+             </table>
+          </div>
+          
+          <div class="timeline-container" id="timeline-view">
+             <canvas id="timeline-canvas"></canvas>
           </div>
+          <div class="timeline-tooltip" id="timeline-tooltip"></div>
           
           <script>
              let scale = 1;
@@ -281,6 +577,540 @@ func (g *Generator) getHTMLTemplate() string {
              window.addEventListener('load', function() {
                 resetZoom();
              });
+             
+             const peekData = {{.Peek}};
+             const peekPanel = document.getElementById('peek-panel');
+             let peekSelected = null;
+             let peekDown = null;
+             
+             function peekDuration(ns) {
+                return (ns < 0 ? '-' : '') + formatFlameDuration(Math.abs(ns));
+             }
+             
+             function peekPercent(ns) {
+                return peekData.total ? (ns / peekData.total * 100).toFixed(2) + '%' : '';
+             }
+             
+             // graphNode returns the SVG group of a function, graphviz titles nodes n<id>
+             function graphNode(id) {
+                for (const node of content.querySelectorAll('g.node')) {
+                   const title = node.querySelector('title');
+                   if (title && title.textContent === 'n' + id) return node;
+                }
+                return null;
+             }
+             
+             function peekRow(cell) {
+                const td = document.createElement('td');
+                td.textContent = cell;
+                return td;
+             }
+             
+             function peekTable(title, calls) {
+                const fragment = document.createDocumentFragment();
+                const heading = document.createElement('h3');
+                heading.textContent = title;
+                fragment.appendChild(heading);
+                if (!calls || calls.length === 0) {
+                   const none = document.createElement('p');
+                   none.textContent = '(none)';
+                   fragment.appendChild(none);
+                   return fragment;
+                }
+                
+                const table = document.createElement('table');
+                for (const call of calls) {
+                   const tr = document.createElement('tr');
+                   tr.appendChild(peekRow(peekDuration(call.v)));
+                   tr.appendChild(peekRow(peekPercent(call.v)));
+                   tr.appendChild(peekRow(call.c + ' calls'));
+                   const name = peekRow(peekData.functions[call.id].n);
+                   name.className = 'name';
+                   name.onclick = function() { selectFunction(call.id); };
+                   tr.appendChild(name);
+                   table.appendChild(tr);
+                }
+                fragment.appendChild(table);
+                return fragment;
+             }
+             
+             // selectFunction shows callers and callees of a function in the side panel
+             function selectFunction(id) {
+                const f = peekData.functions[id];
+                if (!f) return;
+                
+                if (peekSelected) peekSelected.classList.remove('selected');
+                peekSelected = graphNode(id);
+                if (peekSelected) peekSelected.classList.add('selected');
+                
+                peekPanel.innerHTML = '';
+                const close = document.createElement('button');
+                close.className = 'peek-close';
+                close.textContent = '×';
+                close.onclick = closePeek;
+                peekPanel.appendChild(close);
+                
+                const name = document.createElement('h2');
+                name.textContent = f.n;
+                peekPanel.appendChild(name);
+                const summary = document.createElement('p');
+                summary.textContent = 'flat ' + peekDuration(f.s) + ' (' + peekPercent(f.s) + '), cum ' +
+                   peekDuration(f.v) + ' (' + peekPercent(f.v) + '), ' + f.c + ' calls';
+                peekPanel.appendChild(summary);
+                peekPanel.appendChild(peekTable('Callers', f.in));
+                peekPanel.appendChild(peekTable('Callees', f.out));
+                peekPanel.style.display = 'block';
+             }
+             
+             function closePeek() {
+                if (peekSelected) peekSelected.classList.remove('selected');
+                peekSelected = null;
+                peekPanel.style.display = 'none';
+             }
+             
+             if (peekData) {
+                viewport.addEventListener('mousedown', function(e) {
+                   peekDown = {x: e.clientX, y: e.clientY};
+                });
+                
+                // A click selects a node, unless the mouse moved to pan the graph
+                viewport.addEventListener('click', function(e) {
+                   if (peekDown && Math.abs(e.clientX - peekDown.x) + Math.abs(e.clientY - peekDown.y) > 4) return;
+                   const node = e.target.closest && e.target.closest('g.node');
+                   if (!node) return;
+                   const title = node.querySelector('title');
+                   if (title) selectFunction(title.textContent.substring(1));
+                });
+             }
+             
+             const flameData = {{.Flame}};
+             // Percentages are of the call graph baseline, like the call graph and the tree subcommand
+             const flameTotal = flameData ? flameData.t || flameData.v : 1;
+             const flameRowHeight = 18;
+             const flameView = document.getElementById('flame-view');
+             let flameMode = 'flame';
+             let flameZoomed = null;
+             let flameSearch = null;
+             let flameMaxDepth = 0;
+             
+             // Link parents and compute offsets once, x is the start of a node in root value units
+             function prepareFlame(node, parent, depth, x) {
+                node.parent = parent;
+                node.depth = depth;
+                node.x = x;
+                flameMaxDepth = Math.max(flameMaxDepth, depth);
+                let childX = x;
+                for (const child of node.ch || []) {
+                   prepareFlame(child, node, depth + 1, childX);
+                   childX += child.v;
+                }
+             }
+             
+             function showView(view) {
+                const flame = view === 'flame' || view === 'icicle';
+                const panels = {
+                   'graph-view': view === 'graph' ? 'block' : 'none',
+                   'zoom-controls': view === 'graph' ? 'flex' : 'none',
+                   'flame-view': flame ? 'block' : 'none',
+                   'flame-controls': flame ? 'flex' : 'none',
+                   'tree-view': view === 'tree' ? 'block' : 'none',
+                   'tree-controls': view === 'tree' ? 'flex' : 'none',
+                   'timeline-view': view === 'timeline' ? 'block' : 'none',
+                   'timeline-controls': view === 'timeline' ? 'flex' : 'none'
+                };
+                for (const id in panels) {
+                   const panel = document.getElementById(id);
+                   if (panel) panel.style.display = panels[id];
+                }
+                for (const name of ['graph', 'flame', 'icicle', 'tree', 'timeline']) {
+                   const button = document.getElementById('view-' + name);
+                   if (button) button.classList.toggle('active', name === view);
+                }
+                if (flame) {
+                   flameMode = view;
+                   renderFlame();
+                }
+                if (view === 'tree') {
+                   renderTree();
+                }
+                if (view === 'timeline') {
+                   renderTimeline();
+                }
+             }
+             
+             function zoomFlame(node) {
+                flameZoomed = node && node.parent ? node : null;
+                renderFlame();
+             }
+             
+             function searchFlame(value) {
+                flameSearch = null;
+                if (value) {
+                   try {
+                      flameSearch = new RegExp(value);
+                   } catch (e) {
+                      flameSearch = {test: function(name) { return name.indexOf(value) >= 0; }};
+                   }
+                }
+                renderFlame();
+             }
+             
+             // matchedValue sums the time of outermost matching calls, so nested matches are counted once
+             function matchedValue(node) {
+                if (flameSearch.test(node.n)) return node.v;
+                let value = 0;
+                for (const child of node.ch || []) value += matchedValue(child);
+                return value;
+             }
+             
+             function formatFlameDuration(ns) {
+                if (ns >= 1e9) return (ns / 1e9).toFixed(2) + 's';
+                if (ns >= 1e6) return (ns / 1e6).toFixed(2) + 'ms';
+                if (ns >= 1e3) return (ns / 1e3).toFixed(1) + 'µs';
+                return ns + 'ns';
+             }
+             
+             // Warm colors derived from the function name, so a function keeps its color across views
+             function flameColor(name) {
+                let hash = 0;
+                for (let i = 0; i < name.length; i++) hash = (hash * 31 + name.charCodeAt(i)) | 0;
+                hash = Math.abs(hash);
+                return 'hsl(' + (hash % 50) + ', ' + (70 + hash % 25) + '%, ' + (60 + hash % 15) + '%)';
+             }
+             
+             function renderFlame() {
+                if (!flameData || flameView.style.display === 'none') return;
+                
+                const width = flameView.clientWidth;
+                const zoomed = flameZoomed || flameData;
+                const scale = width / zoomed.v;
+                const height = (flameMaxDepth + 1) * flameRowHeight;
+                const fragment = document.createDocumentFragment();
+                
+                function draw(node, left, w) {
+                   const frame = document.createElement('div');
+                   frame.className = 'flame-frame';
+                   if (flameSearch && flameSearch.test(node.n)) frame.classList.add('matched');
+                   frame.style.left = left + 'px';
+                   frame.style.width = w + 'px';
+                   frame.style.top = (flameMode === 'flame' ? height - (node.depth + 1) * flameRowHeight : node.depth * flameRowHeight) + 'px';
+                   frame.style.background = node.parent ? flameColor(node.n) : '#e5e7eb';
+                   if (w > 30) frame.textContent = node.n;
+                   frame.title = node.n + '\n' +
+                      formatFlameDuration(node.v) + ' (' + (node.v / flameTotal * 100).toFixed(2) + '%)' +
+                      (node.parent ? ', self ' + formatFlameDuration(node.s) + ', ' + node.c + ' calls' : '');
+                   frame.onclick = function() { zoomFlame(node); };
+                   fragment.appendChild(frame);
+                }
+                
+                // Callers of the zoomed node span the whole width
+                for (let node = zoomed.parent; node; node = node.parent) {
+                   draw(node, 0, width);
+                }
+                
+                (function walk(node) {
+                   const w = node.v * scale;
+                   if (w < 0.5) return;
+                   draw(node, (node.x - zoomed.x) * scale, w);
+                   for (const child of node.ch || []) walk(child);
+                })(zoomed);
+                
+                flameView.innerHTML = '';
+                const canvas = document.createElement('div');
+                canvas.style.position = 'relative';
+                canvas.style.height = height + 'px';
+                canvas.appendChild(fragment);
+                flameView.appendChild(canvas);
+                
+                const matched = document.getElementById('flame-matched');
+                matched.textContent = flameSearch ? 'Matched ' + (matchedValue(flameData) / flameTotal * 100).toFixed(2) + '%' : '';
+             }
+             
+             if (flameData) {
+                prepareFlame(flameData, null, 0, 0);
+                window.addEventListener('resize', renderFlame);
+             }
+             
+             // The call tree shares call paths with the flame graph, opened paths are rendered as rows
+             const treeBody = document.getElementById('tree-body');
+             const treeHotFraction = 0.05;
+             
+             function treeChildren(node) {
+                if (!node.sorted) {
+                   node.sorted = (node.ch || []).slice().sort(function(a, b) { return b.v - a.v; });
+                }
+                return node.sorted;
+             }
+             
+             function treeCell(text) {
+                const td = document.createElement('td');
+                td.textContent = text;
+                return td;
+             }
+             
+             function renderTree() {
+                if (!flameData) return;
+                
+                const fragment = document.createDocumentFragment();
+                (function walk(node) {
+                   for (const child of treeChildren(node)) {
+                      const tr = document.createElement('tr');
+                      const name = document.createElement('td');
+                      name.className = 'name';
+                      name.style.paddingLeft = (8 + (child.depth - 1) * 16) + 'px';
+                      
+                      const toggle = document.createElement('span');
+                      toggle.className = 'tree-toggle';
+                      if (child.ch && child.ch.length > 0) {
+                         toggle.textContent = child.open ? '▾' : '▸';
+                         tr.onclick = function() {
+                            child.open = !child.open;
+                            renderTree();
+                         };
+                         tr.style.cursor = 'pointer';
+                      }
+                      name.appendChild(toggle);
+                      
+                      const bar = document.createElement('span');
+                      bar.className = 'tree-bar';
+                      bar.style.width = Math.max(1, Math.round(child.v / flameTotal * 60)) + 'px';
+                      name.appendChild(bar);
+                      name.appendChild(document.createTextNode(child.n));
+                      
+                      tr.appendChild(name);
+                      tr.appendChild(treeCell(formatFlameDuration(child.v)));
+                      tr.appendChild(treeCell((child.v / flameTotal * 100).toFixed(2) + '%'));
+                      tr.appendChild(treeCell(formatFlameDuration(child.s)));
+                      tr.appendChild(treeCell((child.s / flameTotal * 100).toFixed(2) + '%'));
+                      tr.appendChild(treeCell(child.c));
+                      fragment.appendChild(tr);
+                      
+                      if (child.open) walk(child);
+                   }
+                })(flameData);
+                
+                treeBody.innerHTML = '';
+                treeBody.appendChild(fragment);
+             }
+             
+             // expandTree opens call paths above treeHotFraction of total time, or closes every path
+             function expandTree(hot) {
+                (function walk(node) {
+                   for (const child of node.ch || []) {
+                      child.open = hot && child.v >= flameTotal * treeHotFraction;
+                      walk(child);
+                   }
+                })(flameData);
+                renderTree();
+             }
+             
+             if (flameData) {
+                expandTree(true);
+             }
+             
+             const timelineData = {{.Timeline}};
+             const timelineRowHeight = 18;
+             const timelineAxisHeight = 20;
+             const timelineMinRange = 10; // microseconds
+             const timelineView = document.getElementById('timeline-view');
+             const timelineCanvas = document.getElementById('timeline-canvas');
+             const timelineTooltip = document.getElementById('timeline-tooltip');
+             let timelineLanes = [];
+             let timelineStart = 0;
+             let timelineEnd = 1;
+             let timelineDrag = null;
+             
+             // Spans are ordered by start, so every depth lane is ordered too
+             function prepareTimeline() {
+                const spans = timelineData.spans;
+                for (let d = 0; d <= timelineData.max_depth; d++) timelineLanes.push([]);
+                for (let i = 0; i < spans.length; i += 4) timelineLanes[spans[i + 1]].push(i);
+                
+                if (timelineData.dropped > 0) {
+                   document.getElementById('timeline-info').textContent = timelineData.dropped +
+                      ' calls shorter than ' + formatFlameDuration(timelineData.min_duration * 1000) + ' not shown';
+                }
+                resetTimeline();
+             }
+             
+             function resetTimeline() {
+                setTimelineRange(0, Math.max(timelineData.duration, timelineMinRange));
+             }
+             
+             function setTimelineRange(start, end) {
+                const range = Math.min(Math.max(end - start, timelineMinRange), Math.max(timelineData.duration, timelineMinRange));
+                start = Math.max(0, Math.min(start, timelineData.duration - range));
+                timelineStart = start;
+                timelineEnd = start + range;
+                renderTimeline();
+             }
+             
+             // tickStep returns a 1, 2 or 5 power of ten step giving about ten ticks
+             function tickStep(range) {
+                const step = Math.pow(10, Math.floor(Math.log10(range / 10)));
+                if (range / step > 50) return step * 5;
+                if (range / step > 20) return step * 2;
+                return step;
+             }
+             
+             function renderTimeline() {
+                if (!timelineData || timelineView.style.display !== 'block') return;
+                
+                const width = timelineView.clientWidth;
+                const height = timelineAxisHeight + (timelineData.max_depth + 1) * timelineRowHeight;
+                const ratio = window.devicePixelRatio || 1;
+                timelineCanvas.width = width * ratio;
+                timelineCanvas.height = height * ratio;
+                timelineCanvas.style.width = width + 'px';
+                timelineCanvas.style.height = height + 'px';
+                
+                const ctx = timelineCanvas.getContext('2d');
+                ctx.setTransform(ratio, 0, 0, ratio, 0, 0);
+                ctx.clearRect(0, 0, width, height);
+                ctx.font = '12px Segoe UI, Tahoma, sans-serif';
+                ctx.textBaseline = 'middle';
+                
+                const scale = width / (timelineEnd - timelineStart);
+                
+                // Time axis
+                const step = tickStep(timelineEnd - timelineStart);
+                ctx.fillStyle = '#6c757d';
+                ctx.strokeStyle = '#e1e5e9';
+                for (let t = Math.ceil(timelineStart / step) * step; t <= timelineEnd; t += step) {
+                   const x = Math.round((t - timelineStart) * scale) + 0.5;
+                   ctx.beginPath();
+                   ctx.moveTo(x, 0);
+                   ctx.lineTo(x, height);
+                   ctx.stroke();
+                   ctx.fillText(formatFlameDuration(t * 1000), x + 3, timelineAxisHeight / 2);
+                }
+                
+                const spans = timelineData.spans;
+                for (let d = 0; d < timelineLanes.length; d++) {
+                   const y = timelineAxisHeight + d * timelineRowHeight;
+                   let laneEnd = -1;
+                   for (const i of timelineLanes[d]) {
+                      const start = spans[i + 2];
+                      const end = spans[i + 3];
+                      if (end < timelineStart) continue;
+                      if (start > timelineEnd) break;
+                      
+                      const x = (start - timelineStart) * scale;
+                      let w = (end - start) * scale;
+                      // Calls narrower than a pixel are merged into one pixel
+                      if (w < 1) {
+                         if (x < laneEnd) continue;
+                         w = 1;
+                      }
+                      laneEnd = x + w;
+                      
+                      const name = timelineData.names[spans[i]];
+                      ctx.fillStyle = flameColor(name);
+                      ctx.fillRect(x, y, Math.max(w - 1, 1), timelineRowHeight - 1);
+                      
+                      if (w > 40) {
+                         ctx.save();
+                         ctx.beginPath();
+                         ctx.rect(Math.max(x, 0), y, w, timelineRowHeight);
+                         ctx.clip();
+                         ctx.fillStyle = '#1a1a1a';
+                         ctx.fillText(name, Math.max(x, 0) + 3, y + timelineRowHeight / 2);
+                         ctx.restore();
+                      }
+                   }
+                }
+             }
+             
+             // spanAt returns the span index under a canvas point or -1
+             function spanAt(x, y) {
+                const d = Math.floor((y - timelineAxisHeight) / timelineRowHeight);
+                if (d < 0 || d >= timelineLanes.length) return -1;
+                
+                const t = timelineStart + x * (timelineEnd - timelineStart) / timelineView.clientWidth;
+                const lane = timelineLanes[d];
+                const spans = timelineData.spans;
+                let lo = 0;
+                let hi = lane.length - 1;
+                let found = -1;
+                while (lo <= hi) {
+                   const mid = (lo + hi) >> 1;
+                   if (spans[lane[mid] + 2] <= t) {
+                      found = lane[mid];
+                      lo = mid + 1;
+                   } else {
+                      hi = mid - 1;
+                   }
+                }
+                return found >= 0 && spans[found + 3] >= t ? found : -1;
+             }
+             
+             function canvasPoint(e) {
+                const rect = timelineCanvas.getBoundingClientRect();
+                return {x: e.clientX - rect.left, y: e.clientY - rect.top};
+             }
+             
+             if (timelineData) {
+                timelineView.addEventListener('wheel', function(e) {
+                   e.preventDefault();
+                   const p = canvasPoint(e);
+                   const range = timelineEnd - timelineStart;
+                   const t = timelineStart + p.x / timelineView.clientWidth * range;
+                   const newRange = range * (e.deltaY > 0 ? 1.2 : 1 / 1.2);
+                   const start = t - p.x / timelineView.clientWidth * newRange;
+                   setTimelineRange(start, start + newRange);
+                });
+                
+                timelineView.addEventListener('mousedown', function(e) {
+                   timelineDrag = {x: e.clientX, start: timelineStart};
+                   timelineView.style.cursor = 'grabbing';
+                });
+                
+                document.addEventListener('mouseup', function() {
+                   timelineDrag = null;
+                   timelineView.style.cursor = 'grab';
+                });
+                
+                timelineView.addEventListener('mousemove', function(e) {
+                   const range = timelineEnd - timelineStart;
+                   if (timelineDrag) {
+                      const start = timelineDrag.start - (e.clientX - timelineDrag.x) * range / timelineView.clientWidth;
+                      setTimelineRange(start, start + range);
+                      return;
+                   }
+                   
+                   const p = canvasPoint(e);
+                   const i = spanAt(p.x, p.y);
+                   if (i < 0) {
+                      timelineTooltip.style.display = 'none';
+                      return;
+                   }
+                   
+                   const spans = timelineData.spans;
+                   timelineTooltip.textContent = timelineData.names[spans[i]] + '\n' +
+                      'Start +' + formatFlameDuration(spans[i + 2] * 1000) + '\n' +
+                      'Duration ' + formatFlameDuration((spans[i + 3] - spans[i + 2]) * 1000);
+                   timelineTooltip.style.left = (e.clientX + 12) + 'px';
+                   timelineTooltip.style.top = (e.clientY + 12) + 'px';
+                   timelineTooltip.style.display = 'block';
+                });
+                
+                timelineView.addEventListener('mouseleave', function() {
+                   timelineTooltip.style.display = 'none';
+                });
+                
+                // Double click zooms to a call
+                timelineView.addEventListener('dblclick', function(e) {
+                   const p = canvasPoint(e);
+                   const i = spanAt(p.x, p.y);
+                   if (i < 0) return;
+                   const spans = timelineData.spans;
+                   const margin = (spans[i + 3] - spans[i + 2]) * 0.05;
+                   setTimelineRange(spans[i + 2] - margin, spans[i + 3] + margin);
+                });
+                
+                window.addEventListener('resize', renderTimeline);
+                prepareTimeline();
+             }
           </script>
        </body>
        </html>`
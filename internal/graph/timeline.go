@@ -0,0 +1,44 @@
+package graph
+
+// timelineData is the timeline encoded for the HTML report.
+// Spans are flattened to name index, depth, start and end quadruplets, times
+// in microseconds from the first event, to keep the HTML small.
+type timelineData struct {
+	Duration    int64    `json:"duration"`
+	Names       []string `json:"names"`
+	Spans       []int64  `json:"spans"`
+	MaxDepth    int      `json:"max_depth"`
+	MinDuration int64    `json:"min_duration"`
+	Dropped     int      `json:"dropped"`
+}
+
+// timeline converts the calls of the call graph, nil when the chronology is unknown
+func (g *Generator) timeline() *timelineData {
+	timeline := g.callGraph.Timeline
+	if timeline == nil || len(timeline.Spans) == 0 {
+		return nil
+	}
+
+	data := &timelineData{
+		Duration:    timeline.End - timeline.Start,
+		Spans:       make([]int64, 0, len(timeline.Spans)*4),
+		MinDuration: timeline.MinDuration,
+		Dropped:     timeline.Dropped,
+	}
+
+	names := make(map[int]int)
+	for _, span := range timeline.Spans {
+		index, ok := names[span.FunctionID]
+		if !ok {
+			index = len(data.Names)
+			names[span.FunctionID] = index
+			data.Names = append(data.Names, g.callGraph.FunctionName(span.FunctionID))
+		}
+
+		data.Spans = append(data.Spans, int64(index), int64(span.Depth),
+			span.Start-timeline.Start, span.End-timeline.Start)
+		data.MaxDepth = max(data.MaxDepth, span.Depth)
+	}
+
+	return data
+}
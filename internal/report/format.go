@@ -0,0 +1,42 @@
+package report
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/supercute/spx-graph/internal/graph"
+	"github.com/supercute/spx-graph/internal/spx"
+)
+
+// formatMetric formats a metric value with its unit
+func formatMetric(metric spx.Metric, value float64) string {
+	switch metric.Unit {
+	case spx.UnitMicroseconds:
+		return graph.FormatDuration(time.Duration(value * float64(time.Microsecond)))
+	case spx.UnitBytes:
+		return graph.FormatBytes(value)
+	default:
+		return fmt.Sprintf("%.0f", value)
+	}
+}
+
+// percent formats value as a percentage of total
+func percent(value, total float64) string {
+	if total == 0 {
+		return "0%"
+	}
+	return fmt.Sprintf("%.2f%%", value/total*100)
+}
+
+// matchingFunctions returns IDs of functions of the call graph with a name matching match,
+// ordered by decreasing inclusive time. The synthetic root never matches.
+func matchingFunctions(cg *spx.CallGraph, match func(name string) bool) []int {
+	var ids []int
+	for id := range cg.Nodes {
+		if id != spx.RootID && match(cg.FunctionName(id)) {
+			ids = append(ids, id)
+		}
+	}
+	sortByDuration(ids, func(id int) time.Duration { return cg.Nodes[id].TotalDuration }, cg.FunctionName)
+	return ids
+}
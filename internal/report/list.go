@@ -0,0 +1,43 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/supercute/spx-graph/internal/spx"
+)
+
+// List writes every recorded metric of functions matching match, self and inclusive.
+// SPX does not record source lines, so unlike go tool pprof -list no source is shown.
+func List(w io.Writer, cg *spx.CallGraph, match *regexp.Regexp) error {
+	ids := matchingFunctions(cg, match.MatchString)
+	if len(ids) == 0 {
+		return fmt.Errorf("no function matches %s", match)
+	}
+
+	for i, id := range ids {
+		node := cg.Nodes[id]
+		name := cg.FunctionName(id)
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "ROUTINE %s %s\n", strings.Repeat("=", 24), name)
+		fmt.Fprintf(w, "  %-24s %12s %12s\n", "metric", "self", "inclusive")
+		fmt.Fprintf(w, "  %-24s %12s %12d\n", "Calls", "", node.CallCount)
+
+		for k, metric := range cg.Metrics {
+			var self, inclusive float64
+			if k < len(node.SelfMetrics) {
+				self = node.SelfMetrics[k]
+			}
+			if k < len(node.Metrics) {
+				inclusive = node.Metrics[k]
+			}
+			fmt.Fprintf(w, "  %-24s %12s %12s\n", metric.Name, formatMetric(metric, self), formatMetric(metric, inclusive))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/supercute/spx-graph/internal/graph"
+	"github.com/supercute/spx-graph/internal/spx"
+)
+
+// Peek writes callers and callees of every function matching match, like go tool pprof -peek.
+// Call times are inclusive times of the calls between the two functions.
+func Peek(w io.Writer, cg *spx.CallGraph, match *regexp.Regexp) error {
+	ids := matchingFunctions(cg, match.MatchString)
+	if len(ids) == 0 {
+		return fmt.Errorf("no function matches %s", match)
+	}
+
+	callers := make(map[int][]*spx.CallEdge)
+	callees := make(map[int][]*spx.CallEdge)
+	for _, edge := range cg.Edges {
+		callers[edge.To] = append(callers[edge.To], edge)
+		callees[edge.From] = append(callees[edge.From], edge)
+	}
+
+	total := float64(cg.Total)
+	for i, id := range ids {
+		node := cg.Nodes[id]
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s\n", cg.FunctionName(id))
+		fmt.Fprintf(w, "  flat %s (%s), cum %s (%s), %d calls\n",
+			graph.FormatDuration(node.SelfDuration), percent(float64(node.SelfDuration), total),
+			graph.FormatDuration(node.TotalDuration), percent(float64(node.TotalDuration), total),
+			node.CallCount)
+
+		for _, section := range []struct {
+			title string
+			edges []*spx.CallEdge
+			other func(edge *spx.CallEdge) int
+		}{
+			{"callers", callers[id], func(edge *spx.CallEdge) int { return edge.From }},
+			{"callees", callees[id], func(edge *spx.CallEdge) int { return edge.To }},
+		} {
+			fmt.Fprintf(w, "  %s:\n", section.title)
+			if len(section.edges) == 0 {
+				fmt.Fprintln(w, "    (none)")
+				continue
+			}
+
+			edges := append([]*spx.CallEdge(nil), section.edges...)
+			sort.Slice(edges, func(i, j int) bool {
+				if edges[i].TotalDuration != edges[j].TotalDuration {
+					return edges[i].TotalDuration > edges[j].TotalDuration
+				}
+				return cg.FunctionName(section.other(edges[i])) < cg.FunctionName(section.other(edges[j]))
+			})
+			for _, edge := range edges {
+				fmt.Fprintf(w, "    %10s %7s %8d  %s\n",
+					graph.FormatDuration(edge.TotalDuration), percent(float64(edge.TotalDuration), total),
+					edge.CallCount, cg.FunctionName(section.other(edge)))
+			}
+		}
+	}
+
+	return nil
+}
+
+// sortByDuration orders IDs by decreasing duration, then by name
+func sortByDuration(ids []int, duration func(id int) time.Duration, name func(id int) string) {
+	sort.Slice(ids, func(i, j int) bool {
+		di, dj := duration(ids[i]), duration(ids[j])
+		if di != dj {
+			return di > dj
+		}
+		return name(ids[i]) < name(ids[j])
+	})
+}
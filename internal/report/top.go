@@ -0,0 +1,125 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/supercute/spx-graph/internal/graph"
+	"github.com/supercute/spx-graph/internal/spx"
+)
+
+// Sort orders of the top report
+const (
+	SortFlat   = "flat"
+	SortCum    = "cum"
+	SortCalls  = "calls"
+	SortMemory = "memory"
+	SortName   = "name"
+)
+
+// SortOrders lists valid sort orders of the top report
+var SortOrders = []string{SortFlat, SortCum, SortCalls, SortMemory, SortName}
+
+// TopOptions selects rows of the top report
+type TopOptions struct {
+	Count int            // show at most this many functions, 0 shows all
+	Sort  string         // one of SortOrders
+	Match *regexp.Regexp // only functions matching this expression, nil matches all
+}
+
+// topRow is a function of the top report
+type topRow struct {
+	name  string
+	stats *spx.FunctionStats
+}
+
+// Top writes a table of functions with self (flat) and inclusive (cum) time like go tool pprof -top.
+// sum% is the running total of flat% in table order. The synthetic root is not a function, it is left out.
+func Top(w io.Writer, cg *spx.CallGraph, opts TopOptions) error {
+	if cg.Stats == nil {
+		return fmt.Errorf("call graph has no function statistics")
+	}
+
+	functions := 0
+	rows := make([]topRow, 0, len(cg.Stats))
+	for id, stats := range cg.Stats {
+		if id == spx.RootID {
+			continue
+		}
+		functions++
+		name := cg.FunctionName(id)
+		if opts.Match != nil && !opts.Match.MatchString(name) {
+			continue
+		}
+		rows = append(rows, topRow{name: name, stats: stats})
+	}
+
+	if err := sortRows(rows, opts.Sort); err != nil {
+		return err
+	}
+
+	shown := rows
+	if opts.Count > 0 && len(shown) > opts.Count {
+		shown = shown[:opts.Count]
+	}
+
+	var flat time.Duration
+	for _, row := range shown {
+		flat += row.stats.SelfDuration
+	}
+	fmt.Fprintf(w, "Showing %d of %d functions, %s of %s total (%s)\n", len(shown), functions,
+		graph.FormatDuration(flat), graph.FormatDuration(cg.Total), percent(float64(flat), float64(cg.Total)))
+	fmt.Fprintf(w, "%10s %7s %7s %10s %7s %8s %10s  %s\n", "flat", "flat%", "sum%", "cum", "cum%", "calls", "memory", "function")
+
+	var sum float64
+	for _, row := range shown {
+		stats := row.stats
+		sum += float64(stats.SelfDuration)
+		fmt.Fprintf(w, "%10s %7s %7s %10s %7s %8d %10s  %s\n",
+			graph.FormatDuration(stats.SelfDuration),
+			percent(float64(stats.SelfDuration), float64(cg.Total)),
+			percent(sum, float64(cg.Total)),
+			graph.FormatDuration(stats.TotalDuration),
+			percent(float64(stats.TotalDuration), float64(cg.Total)),
+			stats.CallCount,
+			graph.FormatBytes(float64(stats.TotalMemory)),
+			row.name)
+	}
+
+	return nil
+}
+
+func sortRows(rows []topRow, order string) error {
+	var less func(a, b *spx.FunctionStats) bool
+	switch order {
+	case SortFlat, "":
+		less = func(a, b *spx.FunctionStats) bool { return a.SelfDuration > b.SelfDuration }
+	case SortCum:
+		less = func(a, b *spx.FunctionStats) bool { return a.TotalDuration > b.TotalDuration }
+	case SortCalls:
+		less = func(a, b *spx.FunctionStats) bool { return a.CallCount > b.CallCount }
+	case SortMemory:
+		less = func(a, b *spx.FunctionStats) bool { return a.TotalMemory > b.TotalMemory }
+	case SortName:
+		less = func(a, b *spx.FunctionStats) bool { return false }
+	default:
+		return fmt.Errorf("unknown sort order %q, expected one of %s", order, strings.Join(SortOrders, ", "))
+	}
+
+	// Ties are ordered by name so the report is stable
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i].stats, rows[j].stats
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return rows[i].name < rows[j].name
+	})
+	return nil
+}
@@ -0,0 +1,63 @@
+package report
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/supercute/spx-graph/internal/spx"
+)
+
+// severalRoots is a profile with two top level calls and time between them
+const severalRoots = `[events]
+0 1 0 0
+0 0 100 0
+1 1 150 0
+1 0 300 0
+
+[functions]
+first
+second
+`
+
+func TestTopHidesSyntheticRoot(t *testing.T) {
+	p, err := spx.ParseReader(strings.NewReader(severalRoots))
+	if err != nil {
+		t.Fatalf("ParseReader: %v", err)
+	}
+	cg := spx.NewAnalyzer(p).BuildCallGraph()
+
+	if cg.Nodes[spx.RootID] == nil {
+		t.Fatalf("graph has no synthetic root")
+	}
+	if want := 300 * time.Microsecond; cg.Total != want {
+		t.Errorf("baseline = %v, want first to last event time %v", cg.Total, want)
+	}
+
+	var out bytes.Buffer
+	if err := Top(&out, cg, TopOptions{Sort: SortFlat}); err != nil {
+		t.Fatalf("Top: %v", err)
+	}
+	if strings.Contains(out.String(), spx.RootName) {
+		t.Errorf("top shows the synthetic root:\n%s", out.String())
+	}
+	if want := "Showing 2 of 2 functions, 250μs of 300μs total (83.33%)"; !strings.Contains(out.String(), want) {
+		t.Errorf("top header:\n%s\nwant %q", out.String(), want)
+	}
+
+	all := regexp.MustCompile(".")
+	for name, report := range map[string]func() error{
+		"list": func() error { return List(&out, cg, all) },
+		"peek": func() error { return Peek(&out, cg, all) },
+	} {
+		out.Reset()
+		if err := report(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if strings.Contains(out.String(), "\n"+spx.RootName+"\n") || strings.Contains(out.String(), "= "+spx.RootName) {
+			t.Errorf("%s shows the synthetic root:\n%s", name, out.String())
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/supercute/spx-graph/internal/graph"
+	"github.com/supercute/spx-graph/internal/spx"
+)
+
+// TreeOptions selects call paths of the tree report
+type TreeOptions struct {
+	MaxDepth    int     // deepest call path level shown, 0 shows all
+	MinFraction float64 // hide call paths below this fraction of total time
+}
+
+// Tree writes the calling context tree: every call path with its inclusive and self time.
+// Call paths hidden by options are summed up in one line per caller. The synthetic root
+// of profiles with several top level calls is shown first, so top level lines add up to it.
+func Tree(w io.Writer, cg *spx.CallGraph, opts TreeOptions) error {
+	if cg.Contexts == nil {
+		return fmt.Errorf("call graph has no call paths")
+	}
+
+	total := float64(cg.Total)
+	fmt.Fprintf(w, "%8s %10s %10s %8s  %s\n", "cum%", "cum", "flat", "calls", "call path")
+
+	level := 0 // indentation of top level calls
+	if root := cg.Nodes[spx.RootID]; root != nil {
+		fmt.Fprintf(w, "%8s %10s %10s %8d  %s\n",
+			percent(float64(root.TotalDuration), total),
+			graph.FormatDuration(root.TotalDuration),
+			graph.FormatDuration(root.SelfDuration),
+			root.CallCount, spx.RootName)
+		level = 1
+	}
+
+	var walk func(n *spx.ContextNode, depth int)
+	walk = func(n *spx.ContextNode, depth int) {
+		indent := strings.Repeat("  ", level+depth)
+
+		children := append([]*spx.ContextNode(nil), n.Children...)
+		sort.SliceStable(children, func(i, j int) bool { return children[i].Duration > children[j].Duration })
+
+		hidden, hiddenCount := 0.0, 0
+		for _, child := range children {
+			if float64(child.Duration) < total*opts.MinFraction || (opts.MaxDepth > 0 && depth >= opts.MaxDepth) {
+				hidden += float64(child.Duration)
+				hiddenCount++
+				continue
+			}
+
+			fmt.Fprintf(w, "%8s %10s %10s %8d  %s%s\n",
+				percent(float64(child.Duration), total),
+				graph.FormatDuration(child.Duration),
+				graph.FormatDuration(child.SelfDuration),
+				child.CallCount, indent, cg.FunctionName(child.FunctionID))
+			walk(child, depth+1)
+		}
+
+		if hiddenCount > 0 {
+			fmt.Fprintf(w, "%8s %10s %10s %8s  %s... %d more call paths\n",
+				percent(hidden, total), "", "", "", indent, hiddenCount)
+		}
+	}
+	walk(cg.Contexts, 0)
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/supercute/spx-graph/internal/graph"
+	"github.com/supercute/spx-graph/internal/spx"
+)
+
+// profileRow is a profile formatted for the list page
+type profileRow struct {
+	Key        string
+	Target     string
+	Time       string
+	WallTime   string
+	PeakMemory string
+	Cached     bool
+}
+
+// sortColumns are the supported list sort keys
+var sortColumns = map[string]func(a, b *spx.ProfileFile) bool{
+	"time": func(a, b *spx.ProfileFile) bool {
+		return a.Time().Before(b.Time())
+	},
+	"target": func(a, b *spx.ProfileFile) bool {
+		return profileTarget(a) < profileTarget(b)
+	},
+	"wall": func(a, b *spx.ProfileFile) bool {
+		return profileWallTime(a) < profileWallTime(b)
+	},
+	"memory": func(a, b *spx.ProfileFile) bool {
+		return profilePeakMemory(a) < profilePeakMemory(b)
+	},
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	files, err := spx.ListProfiles(s.dir)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list profiles: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := query.Get("q")
+	sortBy := query.Get("sort")
+	if sortColumns[sortBy] == nil {
+		sortBy = "time"
+	}
+	desc := query.Get("order") != "asc"
+
+	// Filter by URI, command line or key
+	if filter != "" {
+		needle := strings.ToLower(filter)
+		filtered := files[:0]
+		for _, file := range files {
+			haystack := strings.ToLower(file.Key + " " + profileTarget(&file))
+			if strings.Contains(haystack, needle) {
+				filtered = append(filtered, file)
+			}
+		}
+		files = filtered
+	}
+
+	less := sortColumns[sortBy]
+	sort.SliceStable(files, func(i, j int) bool {
+		if desc {
+			return less(&files[j], &files[i])
+		}
+		return less(&files[i], &files[j])
+	})
+
+	s.mu.Lock()
+	rows := make([]profileRow, 0, len(files))
+	for i := range files {
+		rows = append(rows, s.profileRow(&files[i]))
+	}
+	s.mu.Unlock()
+
+	data := struct {
+		Dir     string
+		Filter  string
+		Sort    string
+		Order   string
+		Columns []listColumn
+		Rows    []profileRow
+	}{
+		Dir:     s.dir,
+		Filter:  filter,
+		Sort:    sortBy,
+		Order:   query.Get("order"),
+		Columns: listColumns(filter, sortBy, desc),
+		Rows:    rows,
+	}
+
+	var buf bytes.Buffer
+	if err := listTemplate.Execute(&buf, data); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to render list: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// listColumn is a sortable list header
+type listColumn struct {
+	Title string
+	URL   string
+	Arrow string
+}
+
+func listColumns(filter, sortBy string, desc bool) []listColumn {
+	columns := []struct{ key, title string }{
+		{"time", "Time"},
+		{"target", "Request"},
+		{"wall", "Wall time"},
+		{"memory", "Peak memory"},
+	}
+
+	result := make([]listColumn, 0, len(columns))
+	for _, c := range columns {
+		params := url.Values{}
+		params.Set("sort", c.key)
+		if filter != "" {
+			params.Set("q", filter)
+		}
+
+		column := listColumn{Title: c.title}
+		if c.key == sortBy {
+			if desc {
+				column.Arrow = "▼"
+				params.Set("order", "asc")
+			} else {
+				column.Arrow = "▲"
+			}
+		}
+		column.URL = "/?" + params.Encode()
+		result = append(result, column)
+	}
+	return result
+}
+
+func (s *Server) profileRow(file *spx.ProfileFile) profileRow {
+	row := profileRow{
+		Key:    file.Key,
+		Target: profileTarget(file),
+		Time:   file.Time().Format("2006-01-02 15:04:05"),
+		Cached: s.cache[file.Key] != nil && s.cache[file.Key].current(file.ModTime, file.Size),
+	}
+	if file.Metadata != nil {
+		row.WallTime = graph.FormatDuration(file.Metadata.WallTime())
+		row.PeakMemory = graph.FormatBytes(float64(file.Metadata.PeakMemoryUsage))
+	}
+	return row
+}
+
+func profileTarget(file *spx.ProfileFile) string {
+	if file.Metadata == nil {
+		return ""
+	}
+	return file.Metadata.Target()
+}
+
+func profileWallTime(file *spx.ProfileFile) time.Duration {
+	if file.Metadata == nil {
+		return 0
+	}
+	return file.Metadata.WallTime()
+}
+
+func profilePeakMemory(file *spx.ProfileFile) int64 {
+	if file.Metadata == nil {
+		return 0
+	}
+	return file.Metadata.PeakMemoryUsage
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"github.com/supercute/spx-graph/internal/graph"
+	"github.com/supercute/spx-graph/internal/spx"
+)
+
+// parseOptions overrides pruning options with nodefraction, edgefraction and nodecount query parameters
+func parseOptions(query url.Values, options graph.Options) (graph.Options, error) {
+	if value := query.Get("nodefraction"); value != "" {
+		fraction, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return options, fmt.Errorf("invalid nodefraction: %s", value)
+		}
+		options.NodeFraction = fraction
+	}
+
+	if value := query.Get("edgefraction"); value != "" {
+		fraction, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return options, fmt.Errorf("invalid edgefraction: %s", value)
+		}
+		options.EdgeFraction = fraction
+	}
+
+	if value := query.Get("nodecount"); value != "" {
+		count, err := strconv.Atoi(value)
+		if err != nil {
+			return options, fmt.Errorf("invalid nodecount: %s", value)
+		}
+		options.NodeCount = count
+	}
+
+	return options, nil
+}
+
+// parseFilter overrides filter expressions with focus, ignore, hide and show query parameters.
+// It reports whether any of them was given, an empty parameter clears the expression.
+func parseFilter(query url.Values, filter *spx.Filter) (*spx.Filter, bool, error) {
+	exprs := map[string]string{}
+	if filter != nil {
+		for name, re := range map[string]*regexp.Regexp{
+			"focus":  filter.Focus,
+			"ignore": filter.Ignore,
+			"hide":   filter.Hide,
+			"show":   filter.Show,
+		} {
+			if re != nil {
+				exprs[name] = re.String()
+			}
+		}
+	}
+
+	changed := false
+	for _, name := range []string{"focus", "ignore", "hide", "show"} {
+		if query.Has(name) {
+			exprs[name] = query.Get(name)
+			changed = true
+		}
+	}
+	if !changed {
+		return filter, false, nil
+	}
+
+	filter, err := spx.NewFilter(exprs["focus"], exprs["ignore"], exprs["hide"], exprs["show"])
+	if err != nil {
+		return nil, false, err
+	}
+	return filter, true, nil
+}
+
+// filterGenerator applies filter query parameters to the call graph of generator
+func filterGenerator(query url.Values, generator *graph.Generator) (*graph.Generator, error) {
+	filter, changed, err := parseFilter(query, generator.Filter())
+	if err != nil || !changed {
+		return generator, err
+	}
+	return generator.WithFilter(filter)
+}
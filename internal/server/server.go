@@ -2,13 +2,66 @@ package server
 
 import (
 	"fmt"
-	"github.com/supercute/spx-graph/internal/graph"
 	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/supercute/spx-graph/internal/graph"
+	"github.com/supercute/spx-graph/internal/spx"
 )
 
 type Server struct {
 	generator *graph.Generator
 	port      int
+
+	// directory mode
+	dir     string
+	options graph.Options // pruning options of directory profiles
+	filter  *spx.Filter   // call graph filter of directory profiles
+	mu      sync.Mutex
+	cache   map[string]*cachedProfile // profile key -> analyzed or in-flight profile
+	uses    uint64                    // profile loads, orders cache entries by last use
+}
+
+// maxCachedProfiles bounds how many analyzed profiles of the data directory are kept in memory
+const maxCachedProfiles = 16
+
+// cachedProfile is a profile of the data directory, analyzed by the first request opening it
+type cachedProfile struct {
+	ready   chan struct{} // closed once the profile is analyzed
+	loaded  *loadedProfile
+	err     error
+	lastUse uint64 // value of Server.uses when the profile was last requested
+}
+
+// cached reports whether the profile was analyzed successfully
+func (c *cachedProfile) cached() bool {
+	select {
+	case <-c.ready:
+		return c.err == nil
+	default:
+		return false
+	}
+}
+
+// current reports whether the profile was analyzed successfully from the file as it is now
+func (c *cachedProfile) current(modTime time.Time, size int64) bool {
+	return c.cached() && c.loaded.file.ModTime.Equal(modTime) && c.loaded.file.Size == size
+}
+
+// changed reports whether the file of an analyzed profile was modified or removed since it was analyzed
+func (c *cachedProfile) changed() bool {
+	info, err := os.Stat(c.loaded.file.Path)
+	return err != nil || !c.current(info.ModTime(), info.Size())
+}
+
+// loadedProfile is an analyzed profile of the data directory
+type loadedProfile struct {
+	file      *spx.ProfileFile // profile file as listed before analyzing it
+	callGraph *spx.CallGraph
+	metadata  *spx.Metadata
+	generator *graph.Generator
 }
 
 func New(generator *graph.Generator, port int) *Server {
@@ -18,30 +71,229 @@ func New(generator *graph.Generator, port int) *Server {
 	}
 }
 
+// NewDir creates a server browsing every profile of a SPX data directory
+func NewDir(dir string, port int, options graph.Options, filter *spx.Filter) *Server {
+	return &Server{
+		port:    port,
+		dir:     dir,
+		options: options,
+		filter:  filter,
+		cache:   make(map[string]*cachedProfile),
+	}
+}
+
+// SetGenerator replaces the graph served by a single profile server, it is safe while serving
+func (s *Server) SetGenerator(generator *graph.Generator) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generator = generator
+}
+
 func (s *Server) Start() error {
-	http.HandleFunc("/", s.handleGraph)
-	http.HandleFunc("/favicon.ico", s.handleFavicon)
+	mux := http.NewServeMux()
+	if s.dir != "" {
+		mux.HandleFunc("/", s.handleList)
+		mux.HandleFunc("/profile", s.handleProfile)
+		mux.HandleFunc("/diff", s.handleDiff)
+	} else {
+		mux.HandleFunc("/", s.handleGraph)
+	}
+	mux.HandleFunc("/favicon.ico", s.handleFavicon)
 
 	addr := fmt.Sprintf(":%d", s.port)
-	return http.ListenAndServe(addr, nil)
+	return http.ListenAndServe(addr, mux)
 }
 
 func (s *Server) handleGraph(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	generator := s.generator
+	s.mu.Unlock()
+
+	generator, err := filterGenerator(r.URL.Query(), generator)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.renderGraph(w, r, generator)
+}
+
+func (s *Server) renderGraph(w http.ResponseWriter, r *http.Request, generator *graph.Generator) {
+	// Query parameters override pruning options for this request
+	options, err := parseOptions(r.URL.Query(), generator.Options())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	generator = generator.WithOptions(options)
+
 	// Generate SVG Graph
-	svg, err := s.generator.GenerateSVG()
+	svg, err := generator.GenerateSVG()
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to generate graph: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	// Generate HTML with SVG
-	html := s.generator.GenerateHTML(svg)
+	html := generator.GenerateHTML(svg)
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(html))
 }
 
+func (s *Server) handleProfile(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+
+	loaded, err := s.loadProfile(key)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to load profile: %v", err), http.StatusNotFound)
+		return
+	}
+
+	generator, err := filterGenerator(r.URL.Query(), loaded.generator)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.renderGraph(w, r, generator)
+}
+
+func (s *Server) handleDiff(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	base, err := s.loadProfile(query.Get("base"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to load base profile: %v", err), http.StatusNotFound)
+		return
+	}
+
+	head, err := s.loadProfile(query.Get("new"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to load new profile: %v", err), http.StatusNotFound)
+		return
+	}
+
+	// Both profiles are filtered before comparing them
+	filter, _, err := parseFilter(query, s.filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	baseGraph, err := base.callGraph.Filter(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	headGraph, err := head.callGraph.Filter(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	generator := graph.NewGenerator(spx.DiffCallGraphs(baseGraph, headGraph), nil)
+	generator.SetMetadata(head.metadata)
+	generator.SetOptions(s.options)
+
+	s.renderGraph(w, r, generator)
+}
+
+// loadProfile parses and analyzes a profile of the directory on first use.
+// The lock is only held to look the cache up, concurrent requests for the
+// same profile wait for the first one, failures are not cached. Profiles whose
+// file changed are analyzed again, and only the most recently used ones are kept.
+func (s *Server) loadProfile(key string) (*loadedProfile, error) {
+	s.mu.Lock()
+	entry, ok := s.cache[key]
+	if ok && entry.cached() && entry.changed() {
+		ok = false
+	}
+	if !ok {
+		entry = &cachedProfile{ready: make(chan struct{})}
+		s.cache[key] = entry
+	}
+	s.uses++
+	entry.lastUse = s.uses
+	s.evict()
+	s.mu.Unlock()
+
+	if !ok {
+		entry.loaded, entry.err = s.analyzeProfile(key)
+		if entry.err != nil {
+			s.mu.Lock()
+			if s.cache[key] == entry {
+				delete(s.cache, key)
+			}
+			s.mu.Unlock()
+		}
+		close(entry.ready)
+	}
+
+	<-entry.ready
+	return entry.loaded, entry.err
+}
+
+// evict drops the least recently used profiles beyond maxCachedProfiles, the lock must be held.
+// Requests waiting for a dropped profile still get it, it is just analyzed again next time.
+func (s *Server) evict() {
+	for len(s.cache) > maxCachedProfiles {
+		var oldest string
+		var oldestUse uint64
+		for key, entry := range s.cache {
+			if oldestUse == 0 || entry.lastUse < oldestUse {
+				oldest, oldestUse = key, entry.lastUse
+			}
+		}
+		delete(s.cache, oldest)
+	}
+}
+
+// analyzeProfile parses and analyzes a profile of the directory
+func (s *Server) analyzeProfile(key string) (*loadedProfile, error) {
+	file, err := s.findProfile(key)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, callGraph, err := spx.AnalyzeFile(file.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	generator := graph.NewGenerator(callGraph, profile.Functions)
+	generator.SetMetadata(profile.Metadata)
+	generator.SetOptions(s.options)
+	if !s.filter.IsEmpty() {
+		if generator, err = generator.WithFilter(s.filter); err != nil {
+			return nil, err
+		}
+	}
+
+	return &loadedProfile{
+		file:      file,
+		callGraph: callGraph,
+		metadata:  profile.Metadata,
+		generator: generator,
+	}, nil
+}
+
+// findProfile looks the key up among directory profiles, so only listed files can be opened
+func (s *Server) findProfile(key string) (*spx.ProfileFile, error) {
+	files, err := spx.ListProfiles(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range files {
+		if files[i].Key == key {
+			return &files[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("profile %q not found", key)
+}
+
 func (s *Server) handleFavicon(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNotFound)
 }
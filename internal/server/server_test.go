@@ -0,0 +1,73 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/supercute/spx-graph/internal/graph"
+)
+
+// writeProfile copies the recursive example profile into dir as name
+func writeProfile(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile("../../examples/recursive.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadProfileCache(t *testing.T) {
+	dir := t.TempDir()
+	path := writeProfile(t, dir, "spx-full-1.txt")
+	s := NewDir(dir, 0, graph.DefaultOptions(), nil)
+
+	first, err := s.loadProfile("spx-full-1")
+	if err != nil {
+		t.Fatalf("loadProfile: %v", err)
+	}
+	again, err := s.loadProfile("spx-full-1")
+	if err != nil {
+		t.Fatalf("loadProfile: %v", err)
+	}
+	if again != first {
+		t.Errorf("unchanged profile was analyzed again")
+	}
+
+	// A rewritten profile is analyzed again
+	modTime := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := s.loadProfile("spx-full-1")
+	if err != nil {
+		t.Fatalf("loadProfile: %v", err)
+	}
+	if changed == first {
+		t.Errorf("changed profile was served from the cache")
+	}
+
+	// Only the most recently used profiles are kept
+	for i := 2; i <= maxCachedProfiles+4; i++ {
+		writeProfile(t, dir, fmt.Sprintf("spx-full-%d.txt", i))
+		if _, err := s.loadProfile(fmt.Sprintf("spx-full-%d", i)); err != nil {
+			t.Fatalf("loadProfile: %v", err)
+		}
+	}
+	if len(s.cache) != maxCachedProfiles {
+		t.Errorf("cache holds %d profiles, want %d", len(s.cache), maxCachedProfiles)
+	}
+	if s.cache["spx-full-1"] != nil {
+		t.Errorf("least recently used profile is still cached")
+	}
+	if s.cache[fmt.Sprintf("spx-full-%d", maxCachedProfiles+4)] == nil {
+		t.Errorf("last used profile is not cached")
+	}
+}
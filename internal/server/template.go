@@ -0,0 +1,143 @@
+package server
+
+import "html/template"
+
+var listTemplate = template.Must(template.New("list").Parse(`<!DOCTYPE html>
+       <html>
+       <head>
+          <meta charset="utf-8">
+          <title>PHP SPX Profiles</title>
+          <style>
+             * {
+                margin: 0;
+                padding: 0;
+                box-sizing: border-box;
+             }
+             
+             body {
+                font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
+                background: #f8f9fa;
+                color: #333;
+             }
+             
+             .header {
+                background: #ffffff;
+                border-bottom: 1px solid #e1e5e9;
+                padding: 16px 24px;
+             }
+             
+             .header h1 {
+                font-size: 24px;
+                font-weight: 600;
+                color: #1a1a1a;
+             }
+             
+             .header p {
+                font-size: 14px;
+                color: #6c757d;
+                margin-top: 4px;
+             }
+             
+             .controls {
+                background: #ffffff;
+                border-bottom: 1px solid #e1e5e9;
+                padding: 12px 24px;
+                display: flex;
+                align-items: center;
+                gap: 8px;
+             }
+             
+             .controls input {
+                border: 1px solid #d1d5db;
+                padding: 6px 12px;
+                font-size: 14px;
+                width: 320px;
+             }
+             
+             .btn {
+                background: #ffffff;
+                border: 1px solid #d1d5db;
+                padding: 6px 12px;
+                cursor: pointer;
+                font-size: 14px;
+             }
+             
+             table {
+                width: 100%;
+                border-collapse: collapse;
+                background: #ffffff;
+                font-size: 14px;
+             }
+             
+             th, td {
+                text-align: left;
+                padding: 8px 24px;
+                border-bottom: 1px solid #e1e5e9;
+             }
+             
+             th a {
+                color: #1a1a1a;
+                text-decoration: none;
+             }
+             
+             td a {
+                color: #0b5cad;
+                text-decoration: none;
+             }
+             
+             tr:hover td {
+                background: #f3f4f6;
+             }
+             
+             .muted {
+                color: #6c757d;
+             }
+          </style>
+       </head>
+       <body>
+          <div class="header">
+             <h1>SPX Profiles</h1>
+             <p>{{.Dir}} · {{len .Rows}} profiles</p>
+          </div>
+          
+          <form class="controls" method="get" action="/">
+             <input type="text" name="q" value="{{.Filter}}" placeholder="Filter by URI, command or key">
+             <input type="hidden" name="sort" value="{{.Sort}}">
+             <input type="hidden" name="order" value="{{.Order}}">
+             <button class="btn" type="submit">Filter</button>
+          </form>
+          
+          <form method="get" action="/diff">
+          <table>
+             <thead>
+                <tr>
+                   <th title="Compare: base / new">Diff</th>
+                   {{range .Columns}}<th><a href="{{.URL}}">{{.Title}} {{.Arrow}}</a></th>{{end}}
+                   <th>Key</th>
+                </tr>
+             </thead>
+             <tbody>
+                {{range .Rows}}
+                <tr>
+                   <td>
+                      <input type="radio" name="base" value="{{.Key}}" title="Base profile">
+                      <input type="radio" name="new" value="{{.Key}}" title="New profile">
+                   </td>
+                   <td>{{.Time}}</td>
+                   <td><a href="/profile?key={{.Key}}">{{if .Target}}{{.Target}}{{else}}{{.Key}}{{end}}</a></td>
+                   <td>{{.WallTime}}</td>
+                   <td>{{.PeakMemory}}</td>
+                   <td class="muted">{{.Key}}{{if .Cached}} · loaded{{end}}</td>
+                </tr>
+                {{else}}
+                <tr><td colspan="6" class="muted">No profiles found</td></tr>
+                {{end}}
+             </tbody>
+          </table>
+          <div class="controls">
+             <button class="btn" type="submit">Compare selected</button>
+             <span class="muted">Pick a base and a new profile in the Diff column</span>
+          </div>
+          </form>
+       </body>
+       </html>`))
@@ -1,11 +1,6 @@
 package spx
 
-import (
-	"fmt"
-	"strconv"
-	"strings"
-	"time"
-)
+import "time"
 
 type Analyzer struct {
 	profile *Profile
@@ -15,207 +10,221 @@ func NewAnalyzer(profile *Profile) *Analyzer {
 	return &Analyzer{profile: profile}
 }
 
-// BuildCallGraph build call graph from profile events
+// BuildCallGraph build call graph from profile events.
+// Calls are aggregated per call path while unwinding the call stack,
+// so the profile is analyzed in a single pass over events.
 func (a *Analyzer) BuildCallGraph() *CallGraph {
-	callInfos := a.buildCallTree()
-	stats := a.calculateStats(callInfos)
+	builder := newTreeBuilder(false)
+	for i := range a.profile.Events {
+		builder.push(&a.profile.Events[i])
+	}
+	builder.finish()
 
-	return a.createCallGraph(callInfos, stats)
+	return a.createCallGraph(builder.tree)
 }
 
-// buildCallTree builds a call tree from events
-func (a *Analyzer) buildCallTree() []CallInfo {
-	var stack []CallInfo
-	var callInfos []CallInfo
-
-	for _, event := range a.profile.Events {
-		funcID := event.FunctionID
+// BuildCallTree reconstructs call frames from profile events.
+// An exit event closes the innermost open call of its function: open calls above it
+// are closed as truncated, exits without any open call are ignored. Both are reported
+// in the tree diagnostics, as well as calls left open at the end of the profile.
+func (a *Analyzer) BuildCallTree() *CallTree {
+	builder := newTreeBuilder(true)
+	for i := range a.profile.Events {
+		builder.push(&a.profile.Events[i])
+	}
+	builder.finish()
 
-		if event.EventType == 1 { // start
-			var parent *int
-			if len(stack) > 0 {
-				parentID := stack[len(stack)-1].FunctionID
-				parent = &parentID
-			}
+	a.resolveDiagnostics(builder.tree)
+	return builder.tree
+}
 
-			callInfo := CallInfo{
-				FunctionID: funcID,
-				Parent:     parent,
-				StartTime:  event.Time,
-				Children:   make([]int, 0),
-			}
+// treeBuilder reconstructs call frames from events fed one at a time
+type treeBuilder struct {
+	tree   *CallTree
+	stack  []*Frame
+	last   *Event
+	events int  // events pushed so far
+	frames int  // frames opened so far
+	retain bool // keep closed frames in the tree, otherwise only call paths survive
+}
 
-			stack = append(stack, callInfo)
-
-		} else if event.EventType == 0 { // end
-			for i := len(stack) - 1; i >= 0; i-- {
-				if stack[i].FunctionID == funcID {
-					callInfo := stack[i]
-					callInfo.EndTime = event.Time
-					callInfo.Duration = time.Duration(event.Time-callInfo.StartTime) * time.Microsecond
-					callInfo.MemoryDelta = event.Memory
-
-					// Add to parent as child
-					if callInfo.Parent != nil {
-						for j := i - 1; j >= 0; j-- {
-							if stack[j].FunctionID == *callInfo.Parent {
-								stack[j].Children = append(stack[j].Children, funcID)
-								break
-							}
-						}
-					}
-
-					callInfos = append(callInfos, callInfo)
-
-					// Remove from stack
-					stack = append(stack[:i], stack[i+1:]...)
-					break
-				}
-			}
-		}
+// newTreeBuilder creates a builder, closed frames are always aggregated in the calling context tree
+func newTreeBuilder(retain bool) *treeBuilder {
+	return &treeBuilder{
+		tree: &CallTree{
+			Roots:       make([]*Frame, 0),
+			Frames:      make([]*Frame, 0),
+			Contexts:    newContextRoot(),
+			Timeline:    &Timeline{},
+			Diagnostics: &Diagnostics{},
+		},
+		retain: retain,
 	}
-
-	return callInfos
 }
 
-// calculateStats calculate statistics for each function
-func (a *Analyzer) calculateStats(callInfos []CallInfo) map[int]*FunctionStats {
-	stats := make(map[int]*FunctionStats)
-
-	for _, call := range callInfos {
-		funcID := call.FunctionID
+// push applies the next event to the call stack
+func (b *treeBuilder) push(event *Event) {
+	index := b.events
+	b.events++
+	b.last = event
 
-		if stats[funcID] == nil {
-			stats[funcID] = &FunctionStats{}
+	if index == 0 {
+		b.tree.Timeline.Start = event.Time
+	}
+	b.tree.Timeline.End = event.Time
+
+	if event.EventType == 1 { // start
+		frame := &Frame{
+			ID:          b.frames,
+			FunctionID:  event.FunctionID,
+			StartTime:   event.Time,
+			StartEvent:  index,
+			MemoryDelta: event.Memory, // start value until exit
+			Children:    make([]*Frame, 0),
+			Metrics:     append([]float64(nil), event.Metrics...), // start values until exit
+			SelfMetrics: make([]float64, len(event.Metrics)),
+		}
+		b.frames++
+
+		if len(b.stack) > 0 {
+			parent := b.stack[len(b.stack)-1]
+			frame.Parent = parent
+			frame.Depth = parent.Depth + 1
+			frame.context = parent.context.child(frame.FunctionID)
+			if b.retain {
+				parent.Children = append(parent.Children, frame)
+			}
+		} else {
+			frame.context = b.tree.Contexts.child(frame.FunctionID)
+			if b.retain {
+				b.tree.Roots = append(b.tree.Roots, frame)
+			}
 		}
 
-		stats[funcID].TotalDuration += call.Duration
-		stats[funcID].CallCount++
-		stats[funcID].TotalMemory += call.MemoryDelta
-
-		if call.Duration > stats[funcID].MaxDuration {
-			stats[funcID].MaxDuration = call.Duration
+		if b.retain {
+			b.tree.Frames = append(b.tree.Frames, frame)
 		}
+		b.stack = append(b.stack, frame)
 
-		// Calculate self-time (time excluding child calls)
-		selfTime := call.Duration
-		for _, childID := range call.Children {
-			for _, childCall := range callInfos {
-				if childCall.FunctionID == childID &&
-					childCall.Parent != nil &&
-					*childCall.Parent == funcID &&
-					childCall.StartTime >= call.StartTime &&
-					childCall.EndTime <= call.EndTime {
-					selfTime -= childCall.Duration
-				}
-			}
+	} else if event.EventType == 0 { // end
+		j := len(b.stack) - 1
+		for j >= 0 && b.stack[j].FunctionID != event.FunctionID {
+			j--
 		}
-		if selfTime > 0 {
-			stats[funcID].SelfDuration += selfTime
+
+		diagnostics := b.tree.Diagnostics
+		if j < 0 {
+			diagnostics.UnmatchedExits++
+			diagnostics.addProblem(problem{kind: problemUnmatchedExit, event: index, function: event.FunctionID})
+			return
 		}
-	}
 
-	return stats
-}
+		// Calls above the matching one never got their exit event
+		for len(b.stack)-1 > j {
+			frame := b.stack[len(b.stack)-1]
+			b.stack = b.stack[:len(b.stack)-1]
+			b.closeFrame(frame, event, true)
 
-// createCallGraph creates the call graph structure
-func (a *Analyzer) createCallGraph(callInfos []CallInfo, stats map[int]*FunctionStats) *CallGraph {
-	nodes := make(map[int]*CallNode)
-
-	// Calculate total execution time
-	var totalTime time.Duration
-	for _, stat := range stats {
-		if stat.TotalDuration > totalTime {
-			totalTime = stat.TotalDuration
+			diagnostics.MissingExits++
+			diagnostics.addProblem(problem{kind: problemMissingExit, event: index, function: event.FunctionID,
+				frame: frame.ID, frameFunction: frame.FunctionID})
 		}
+
+		b.closeFrame(b.stack[j], event, false)
+		b.stack = b.stack[:j]
 	}
+}
 
-	// Create nodes
-	for funcID, stat := range stats {
-		funcName := a.profile.Functions[funcID]
-		if funcName == "" {
-			funcName = fmt.Sprintf("func_%d", funcID)
-		}
+// finish closes calls left open by a truncated profile
+func (b *treeBuilder) finish() {
+	for len(b.stack) > 0 {
+		frame := b.stack[len(b.stack)-1]
+		b.stack = b.stack[:len(b.stack)-1]
+		b.closeFrame(frame, b.last, true)
 
-		// Split long names
-		if len(funcName) > 50 {
-			parts := strings.Split(funcName, "/")
-			if len(parts) > 1 {
-				funcName = ".../" + parts[len(parts)-1]
-			}
-		}
+		b.tree.Diagnostics.UnclosedFrames++
+		b.tree.Diagnostics.addProblem(problem{kind: problemUnclosedFrame,
+			frame: frame.ID, frameFunction: frame.FunctionID})
+	}
 
-		percentage := 0.0
-		selfPercentage := 0.0
-		if totalTime > 0 {
-			percentage = float64(stat.TotalDuration) / float64(totalTime) * 100
-			selfPercentage = float64(stat.SelfDuration) / float64(totalTime) * 100
-		}
+	b.tree.Timeline.sort()
+}
 
-		nodes[funcID] = &CallNode{
-			FunctionID:     funcID,
-			Name:           funcName,
-			TotalDuration:  stat.TotalDuration,
-			SelfDuration:   stat.SelfDuration,
-			CallCount:      stat.CallCount,
-			TotalMemory:    stat.TotalMemory,
-			Percentage:     percentage,
-			SelfPercentage: selfPercentage,
-		}
+// closeFrame sets frame end values from the event closing it.
+// Children are always closed before their parent, so self values are final here.
+func (b *treeBuilder) closeFrame(frame *Frame, event *Event, truncated bool) {
+	frame.EndTime = event.Time
+	frame.EndEvent = b.events - 1 // the event being pushed, or the last one when finishing
+	frame.Duration = time.Duration(event.Time-frame.StartTime) * time.Microsecond
+	frame.MemoryDelta = event.Memory - frame.MemoryDelta
+	frame.Truncated = truncated
+	for k, value := range event.Metrics {
+		frame.Metrics[k] = value - frame.Metrics[k]
 	}
 
-	// Create edges
-	edgeStats := make(map[string]struct {
-		count    int
-		duration time.Duration
-	})
-
-	for _, call := range callInfos {
-		if call.Parent != nil {
-			key := fmt.Sprintf("%d->%d", *call.Parent, call.FunctionID)
-			edge := edgeStats[key]
-			edge.count++
-			edge.duration += call.Duration
-			edgeStats[key] = edge
-		}
+	// Self values start at minus the children inclusive values
+	frame.SelfDuration += frame.Duration
+	addMetrics(frame.SelfMetrics, frame.Metrics)
+
+	if parent := frame.Parent; parent != nil {
+		parent.SelfDuration -= frame.Duration
+		subMetrics(parent.SelfMetrics, frame.Metrics)
 	}
 
-	var edges []*CallEdge
-	for key, edge := range edgeStats {
-		parts := strings.Split(key, "->")
-		if len(parts) != 2 {
-			continue
-		}
+	frame.context.add(frame)
+	b.tree.Timeline.add(frame)
+}
 
-		from, _ := strconv.Atoi(parts[0])
-		to, _ := strconv.Atoi(parts[1])
+// functionName returns the profile function name or a placeholder
+func (a *Analyzer) functionName(funcID int) string {
+	return a.profile.FunctionName(funcID)
+}
 
-		percentage := 0.0
-		if totalTime > 0 {
-			percentage = float64(edge.duration) / float64(totalTime) * 100
-		}
+// resolveDiagnostics adds event lines skipped by the scanner to the tree diagnostics
+// and describes problems, function names may only be known now when events were streamed
+func (a *Analyzer) resolveDiagnostics(tree *CallTree) {
+	tree.Diagnostics.SkippedLines = a.profile.SkippedLines
+	tree.Diagnostics.skipMessages = a.profile.skipMessages
+	tree.Diagnostics.resolve(a.functionName)
+}
 
-		edges = append(edges, &CallEdge{
-			From:          from,
-			To:            to,
-			CallCount:     edge.count,
-			TotalDuration: edge.duration,
-			Percentage:    percentage,
-		})
+// baseline returns the percentage baseline: the time between first and last event,
+// or the metadata wall time when events carry no time, 0 when neither is known
+func (a *Analyzer) baseline(tree *CallTree) time.Duration {
+	if d := tree.Timeline.Duration(); d > 0 {
+		return d
+	}
+	if a.profile.Metadata != nil {
+		return a.profile.Metadata.WallTime()
 	}
+	return 0
+}
+
+// createCallGraph creates the call graph structure
+func (a *Analyzer) createCallGraph(tree *CallTree) *CallGraph {
+	a.resolveDiagnostics(tree)
 
-	// Find root node
-	root := 0
-	for _, call := range callInfos {
-		if call.Parent == nil {
-			root = call.FunctionID
-			break
+	callGraph := newCallGraph(tree.Contexts, a.profile.Functions, a.profile.Metrics, nil, a.baseline(tree))
+	callGraph.Timeline = tree.Timeline
+	callGraph.Diagnostics = tree.Diagnostics
+
+	return callGraph
+}
+
+// addMetrics adds src metric values to dst
+func addMetrics(dst, src []float64) {
+	for i := range dst {
+		if i < len(src) {
+			dst[i] += src[i]
 		}
 	}
+}
 
-	return &CallGraph{
-		Nodes: nodes,
-		Edges: edges,
-		Root:  root,
+// subMetrics subtracts src metric values from dst
+func subMetrics(dst, src []float64) {
+	for i := range dst {
+		if i < len(src) {
+			dst[i] -= src[i]
+		}
 	}
 }
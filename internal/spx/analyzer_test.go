@@ -0,0 +1,215 @@
+package spx
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parseEvents parses a profile made of event lines and the functions "a", "b" and "c"
+func parseEvents(t *testing.T, events string) *Profile {
+	t.Helper()
+	p, err := ParseReader(strings.NewReader("[events]\n" + events + "\n[functions]\na\nb\nc\n"))
+	if err != nil {
+		t.Fatalf("ParseReader: %v", err)
+	}
+	return p
+}
+
+func TestBuildCallTreeRecursive(t *testing.T) {
+	p, err := ParseProfile("../../examples/recursive.txt")
+	if err != nil {
+		t.Fatalf("ParseProfile: %v", err)
+	}
+	analyzer := NewAnalyzer(p)
+	tree := analyzer.BuildCallTree()
+
+	if !tree.Diagnostics.OK() {
+		t.Errorf("diagnostics = %s, want well nested", tree.Diagnostics)
+	}
+	if len(tree.Roots) != 1 || tree.Roots[0].ID != 0 {
+		t.Fatalf("roots = %v, want frame 0 only", tree.Roots)
+	}
+
+	tests := []struct {
+		function string
+		parent   int // -1 for the root frame
+		depth    int
+		duration time.Duration
+		self     time.Duration
+	}{
+		{"main.php", -1, 0, 1500 * time.Microsecond, 400 * time.Microsecond},
+		{"Math::factorial", 0, 1, 1000 * time.Microsecond, 300 * time.Microsecond},
+		{"Math::multiply", 1, 2, 100 * time.Microsecond, 100 * time.Microsecond},
+		{"Math::factorial", 1, 2, 600 * time.Microsecond, 300 * time.Microsecond},
+		{"Math::multiply", 3, 3, 100 * time.Microsecond, 100 * time.Microsecond},
+		{"Math::factorial", 3, 3, 200 * time.Microsecond, 200 * time.Microsecond},
+		{"Logger::log", 0, 1, 100 * time.Microsecond, 100 * time.Microsecond},
+	}
+	if len(tree.Frames) != len(tests) {
+		t.Fatalf("got %d frames, want %d", len(tree.Frames), len(tests))
+	}
+
+	for id, tt := range tests {
+		frame := tree.Frames[id]
+		if name := analyzer.functionName(frame.FunctionID); name != tt.function {
+			t.Errorf("frame %d: function = %s, want %s", id, name, tt.function)
+		}
+		parent := -1
+		if frame.Parent != nil {
+			parent = frame.Parent.ID
+		}
+		if parent != tt.parent {
+			t.Errorf("frame %d: parent = %d, want %d", id, parent, tt.parent)
+		}
+		if frame.Depth != tt.depth {
+			t.Errorf("frame %d: depth = %d, want %d", id, frame.Depth, tt.depth)
+		}
+		if frame.Duration != tt.duration {
+			t.Errorf("frame %d: duration = %v, want %v", id, frame.Duration, tt.duration)
+		}
+		if frame.SelfDuration != tt.self {
+			t.Errorf("frame %d: self duration = %v, want %v", id, frame.SelfDuration, tt.self)
+		}
+		if frame.Truncated {
+			t.Errorf("frame %d: truncated, want closed by its exit event", id)
+		}
+	}
+
+	// Self time of recursive calls excludes their nested activations
+	self := analyzer.BuildCallGraph().Nodes[1].SelfDuration
+	if want := 800 * time.Microsecond; self != want {
+		t.Errorf("Math::factorial self duration = %v, want %v", self, want)
+	}
+}
+
+func TestBuildCallTreeDiagnostics(t *testing.T) {
+	tests := []struct {
+		name      string
+		events    string
+		want      Diagnostics
+		truncated []int // IDs of frames closed without their exit event
+		message   string
+	}{
+		{
+			name:    "unmatched exit",
+			events:  "0 1 0 0\n1 0 10 0\n0 0 20 0",
+			want:    Diagnostics{UnmatchedExits: 1},
+			message: "event #1: exit of b without open call",
+		},
+		{
+			name:      "missing exit",
+			events:    "0 1 0 0\n1 1 10 0\n0 0 20 0",
+			want:      Diagnostics{MissingExits: 1},
+			truncated: []int{1},
+			message:   "event #2: exit of a closes call #1 of b without exit event",
+		},
+		{
+			name:      "unclosed frame",
+			events:    "0 1 0 0\n1 1 10 0\n1 0 20 0",
+			want:      Diagnostics{UnclosedFrames: 1},
+			truncated: []int{0},
+			message:   "call #0 of a still open at end of profile",
+		},
+		{
+			name:    "skipped line",
+			events:  "0 1 0 0\n0 x 10 0\n0 0 20 0",
+			want:    Diagnostics{SkippedLines: 1},
+			message: "line 3: ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := NewAnalyzer(parseEvents(t, tt.events)).BuildCallTree()
+			d := tree.Diagnostics
+
+			if d.SkippedLines != tt.want.SkippedLines || d.UnmatchedExits != tt.want.UnmatchedExits ||
+				d.MissingExits != tt.want.MissingExits || d.UnclosedFrames != tt.want.UnclosedFrames {
+				t.Errorf("diagnostics = %s, want %s", d, &tt.want)
+			}
+			if d.OK() {
+				t.Errorf("diagnostics OK, want problems reported")
+			}
+			if len(d.Messages) != 1 || !strings.HasPrefix(d.Messages[0], tt.message) {
+				t.Errorf("messages = %q, want %q", d.Messages, tt.message)
+			}
+
+			for _, frame := range tree.Frames {
+				want := false
+				for _, id := range tt.truncated {
+					want = want || id == frame.ID
+				}
+				if frame.Truncated != want {
+					t.Errorf("frame %d: truncated = %t, want %t", frame.ID, frame.Truncated, want)
+				}
+				if frame.Duration < 0 || frame.SelfDuration < 0 {
+					t.Errorf("frame %d: duration = %v, self = %v, want positive values",
+						frame.ID, frame.Duration, frame.SelfDuration)
+				}
+			}
+		})
+	}
+}
+
+// benchmarkSizes are the event counts of generated profiles, to check that analysis scales linearly
+var benchmarkSizes = []int{10_000, 100_000, 1_000_000}
+
+// generateProfile returns a profile text of about n events.
+// The deep shape nests calls 1000 levels deep through 3 recursive functions,
+// the wide shape is a single root calling 1000 functions in turn.
+func generateProfile(n int, shape string) string {
+	const functions = 1000
+
+	var sb strings.Builder
+	sb.WriteString("[events]\n")
+	events := 0
+	event := func(funcID, eventType int) {
+		fmt.Fprintf(&sb, "%d %d %d %d\n", funcID, eventType, events, 0)
+		events++
+	}
+
+	switch shape {
+	case "deep":
+		for events < n {
+			for level := 0; level < functions; level++ {
+				event(level%3, 1)
+			}
+			for level := functions - 1; level >= 0; level-- {
+				event(level%3, 0)
+			}
+		}
+	case "wide":
+		event(0, 1)
+		for i := 0; events < n-1; i++ {
+			event(1+i%functions, 1)
+			event(1+i%functions, 0)
+		}
+		event(0, 0)
+	}
+
+	sb.WriteString("\n[functions]\n")
+	for id := 0; id <= functions; id++ {
+		fmt.Fprintf(&sb, "func_%d\n", id)
+	}
+	return sb.String()
+}
+
+func BenchmarkBuildCallGraph(b *testing.B) {
+	for _, shape := range []string{"deep", "wide"} {
+		for _, n := range benchmarkSizes {
+			b.Run(fmt.Sprintf("%s/%d", shape, n), func(b *testing.B) {
+				p, err := ParseReader(strings.NewReader(generateProfile(n, shape)))
+				if err != nil {
+					b.Fatalf("ParseReader: %v", err)
+				}
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					NewAnalyzer(p).BuildCallGraph()
+				}
+			})
+		}
+	}
+}
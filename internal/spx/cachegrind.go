@@ -0,0 +1,194 @@
+package spx
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// isCallgrind reports whether buffered input starts like a callgrind file, as written by the Xdebug profiler
+func isCallgrind(r *bufio.Reader) bool {
+	head, _ := r.Peek(64)
+	head = bytes.TrimLeft(head, " \t\r\n")
+	return bytes.HasPrefix(head, []byte("# callgrind format")) || bytes.HasPrefix(head, []byte("version:"))
+}
+
+// cachegrindEvents maps Xdebug event names to SPX metrics and the factor converting their values
+var cachegrindEvents = map[string]struct {
+	key    string
+	factor float64
+}{
+	"Time_(10ns)":    {"wt", 0.01}, // Xdebug 3
+	"Time":           {"wt", 1},    // Xdebug 2, microseconds
+	"Memory_(bytes)": {"zm", 1},
+	"Memory":         {"zm", 1},
+}
+
+// cachegrindParser reads the functions and calls of a callgrind file
+type cachegrindParser struct {
+	*costProfile
+
+	factors   []float64
+	positions int
+	files     map[string]string // "(id)" -> compressed file name
+	names     map[string]string // "(id)" -> compressed function name
+
+	fn     int        // current function
+	callee int        // callee of the last calls= line
+	call   *edgeStats // pending call cost line, nil for self cost lines
+}
+
+// analyzeCallgrind builds the call graph of a callgrind profile, like cachegrind.out files of Xdebug
+func analyzeCallgrind(reader *bufio.Reader, metadata *Metadata) (*Profile, *CallGraph, error) {
+	p := &cachegrindParser{
+		costProfile: newCostProfile(),
+		positions:   1,
+		files:       make(map[string]string),
+		names:       make(map[string]string),
+		fn:          -1,
+	}
+
+	lineNumber := 0
+	for {
+		line, err := readLine(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		lineNumber++
+
+		if err := p.parseLine(strings.TrimSpace(line)); err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+	}
+
+	if p.metrics == nil {
+		return nil, nil, fmt.Errorf("invalid callgrind file: no events line")
+	}
+
+	profile := p.profile(metadata)
+	return profile, p.callGraph(profile), nil
+}
+
+func (p *cachegrindParser) parseLine(line string) error {
+	if line == "" || line[0] == '#' {
+		return nil
+	}
+
+	// Cost lines start with a position: a number, a relative +n/-n or * for the same position
+	if c := line[0]; c >= '0' && c <= '9' || c == '+' || c == '-' || c == '*' {
+		return p.parseCosts(line)
+	}
+
+	if key, value, ok := strings.Cut(line, "="); ok && !strings.Contains(key, ":") {
+		switch key {
+		case "fl", "fi", "fe", "cfl", "cfi", "cfe":
+			p.expand(p.files, value)
+		case "fn":
+			p.fn = p.function(p.expand(p.names, value))
+			p.call = nil
+		case "cfn":
+			p.callee = p.function(p.expand(p.names, value))
+		case "calls":
+			if p.fn < 0 {
+				return fmt.Errorf("calls before fn")
+			}
+			fields := strings.Fields(value)
+			if len(fields) == 0 {
+				return fmt.Errorf("invalid calls: %s", value)
+			}
+			count, err := strconv.Atoi(fields[0])
+			if err != nil {
+				return fmt.Errorf("invalid calls: %s", value)
+			}
+			p.call = p.costProfile.call(p.fn, p.callee)
+			p.call.count += count
+		}
+		return nil
+	}
+
+	key, value, _ := strings.Cut(line, ":")
+	value = strings.TrimSpace(value)
+	switch key {
+	case "events":
+		for _, name := range strings.Fields(value) {
+			event, ok := cachegrindEvents[name]
+			if !ok {
+				event.key, event.factor = name, 1
+			}
+			p.metrics = append(p.metrics, LookupMetric(event.key))
+			p.factors = append(p.factors, event.factor)
+		}
+	case "positions":
+		p.positions = len(strings.Fields(value))
+	case "summary", "totals":
+		costs, err := p.costs(strings.Fields(value))
+		if err != nil {
+			return err
+		}
+		p.total = costs
+	}
+	return nil
+}
+
+// parseCosts adds a cost line to the current function or to the pending call
+func (p *cachegrindParser) parseCosts(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) < p.positions || p.fn < 0 {
+		return fmt.Errorf("invalid cost line: %s", line)
+	}
+
+	costs, err := p.costs(fields[p.positions:])
+	if err != nil {
+		return err
+	}
+
+	if p.call != nil {
+		addMetrics(p.call.metrics, costs)
+		p.call = nil
+		return nil
+	}
+
+	p.addSelf(p.fn, costs)
+	return nil
+}
+
+// costs converts cost fields to metric values, missing trailing costs are 0
+func (p *cachegrindParser) costs(fields []string) ([]float64, error) {
+	costs := make([]float64, len(p.metrics))
+	for i, field := range fields {
+		if i >= len(costs) {
+			break
+		}
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cost: %s", field)
+		}
+		costs[i] = value * p.factors[i]
+	}
+	return costs, nil
+}
+
+// expand resolves "(id) name" and "(id)" name compression
+func (p *cachegrindParser) expand(ids map[string]string, value string) string {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "(") {
+		return value
+	}
+	end := strings.IndexByte(value, ')')
+	if end < 0 {
+		return value
+	}
+
+	id := value[:end+1]
+	if name := strings.TrimSpace(value[end+1:]); name != "" {
+		ids[id] = name
+		return name
+	}
+	return ids[id]
+}
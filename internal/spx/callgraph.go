@@ -0,0 +1,288 @@
+package spx
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RootID is the function ID of the synthetic root node calling every top level function
+const RootID = -1
+
+// RootName is the name of the synthetic root node
+const RootName = "<root>"
+
+// edgeKey identifies a caller -> callee edge
+type edgeKey struct {
+	from, to int
+}
+
+type edgeStats struct {
+	count    int
+	duration time.Duration
+	metrics  []float64
+}
+
+// aggregator accumulates function and edge statistics of call paths.
+// Inclusive values of recursive calls are already part of the outermost activation,
+// so they are only counted for functions and edges not active on the current path.
+type aggregator struct {
+	metricCount int
+	stats       map[int]*FunctionStats
+	edges       map[edgeKey]*edgeStats
+
+	activeFunctions map[int]int     // function ID -> activations on the current path
+	activeEdges     map[edgeKey]int // edge -> activations on the current path
+}
+
+// Filter rebuilds the call graph from all call paths restricted by filter.
+// Filters always apply to the unfiltered graph and percentages keep its baseline.
+// An empty filter returns the unfiltered graph, as call paths of callgrind and XHProf
+// profiles are approximations of their exact function and call costs.
+func (cg *CallGraph) Filter(filter *Filter) (*CallGraph, error) {
+	if cg.unfiltered != nil {
+		return cg.unfiltered.Filter(filter)
+	}
+	if filter.IsEmpty() {
+		return cg, nil
+	}
+	if cg.calls == nil {
+		return nil, errors.New("call graph has no call paths to filter")
+	}
+
+	filtered := newCallGraph(cg.calls, cg.Functions, cg.Metrics, filter, cg.Total)
+	filtered.Timeline = cg.Timeline
+	filtered.Diagnostics = cg.Diagnostics
+	filtered.unfiltered = cg
+
+	return filtered, nil
+}
+
+// newCallGraph aggregates the calling context tree into a call graph.
+// filter may be nil, total is the percentage baseline, 0 uses the time of top level calls.
+func newCallGraph(calls *ContextNode, functions map[int]string, metrics []Metric, filter *Filter, total time.Duration) *CallGraph {
+	contexts := filter.apply(calls, functions)
+	if total <= 0 {
+		for _, child := range contexts.Children {
+			total += max(child.Duration, 0)
+		}
+	}
+
+	agg := &aggregator{
+		metricCount: len(metrics),
+		stats:       make(map[int]*FunctionStats),
+		edges:       make(map[edgeKey]*edgeStats),
+
+		activeFunctions: make(map[int]int),
+		activeEdges:     make(map[edgeKey]int),
+	}
+
+	for _, child := range contexts.Children {
+		agg.walk(child, -1)
+	}
+	// Filtered graphs only keep some call paths, so the run as a whole is not shown
+	root := filter.IsEmpty() && agg.addRoot(total, contexts.Children)
+
+	callGraph := agg.createCallGraph(total, functions)
+	callGraph.Metrics = metrics
+	callGraph.Functions = functions
+	callGraph.Contexts = contexts
+	callGraph.calls = calls
+
+	// Root is the synthetic root or the first top level function
+	if root {
+		callGraph.Root = RootID
+	} else if len(contexts.Children) > 0 {
+		callGraph.Root = contexts.Children[0].FunctionID
+	}
+
+	return callGraph
+}
+
+// addRoot adds the synthetic root node when there are several top level calls or time
+// spent outside of them, so that percentages of its callees add up to the baseline.
+// It reports whether the root was added.
+func (agg *aggregator) addRoot(total time.Duration, calls []*ContextNode) bool {
+	var called time.Duration
+	for _, call := range calls {
+		called += max(call.Duration, 0)
+	}
+	if len(calls) == 0 || (len(calls) == 1 && called >= total) {
+		return false
+	}
+
+	stat := agg.stat(RootID)
+	stat.TotalDuration = max(total, called)
+	stat.SelfDuration = stat.TotalDuration - called
+	stat.MaxDuration = stat.TotalDuration
+	stat.CallCount = 1
+	for _, call := range calls {
+		stat.TotalMemory += call.Memory
+		addMetrics(stat.Metrics, call.Metrics)
+
+		agg.edges[edgeKey{from: RootID, to: call.FunctionID}] = &edgeStats{
+			count:    call.CallCount,
+			duration: call.Duration,
+			metrics:  append([]float64(nil), call.Metrics...),
+		}
+	}
+
+	return true
+}
+
+// walk aggregates the subtree of a call path called from caller, -1 for top level calls
+func (agg *aggregator) walk(n *ContextNode, caller int) {
+	stat := agg.stat(n.FunctionID)
+	if agg.activeFunctions[n.FunctionID] == 0 {
+		stat.TotalDuration += n.Duration
+		stat.TotalMemory += n.Memory
+		addMetrics(stat.Metrics, n.Metrics)
+	}
+	stat.CallCount += n.CallCount
+	addMetrics(stat.SelfMetrics, n.SelfMetrics)
+	if n.SelfDuration > 0 {
+		stat.SelfDuration += n.SelfDuration
+	}
+	if n.MaxDuration > stat.MaxDuration {
+		stat.MaxDuration = n.MaxDuration
+	}
+
+	key := edgeKey{from: caller, to: n.FunctionID}
+	if caller >= 0 {
+		edge := agg.edges[key]
+		if edge == nil {
+			edge = &edgeStats{metrics: make([]float64, agg.metricCount)}
+			agg.edges[key] = edge
+		}
+
+		edge.count += n.CallCount
+		if agg.activeEdges[key] == 0 {
+			edge.duration += n.Duration
+			addMetrics(edge.metrics, n.Metrics)
+		}
+	}
+
+	agg.activeFunctions[n.FunctionID]++
+	agg.activeEdges[key]++
+	for _, child := range n.Children {
+		agg.walk(child, n.FunctionID)
+	}
+	agg.activeFunctions[n.FunctionID]--
+	agg.activeEdges[key]--
+}
+
+func (agg *aggregator) stat(funcID int) *FunctionStats {
+	stat := agg.stats[funcID]
+	if stat == nil {
+		stat = &FunctionStats{
+			Metrics:     make([]float64, agg.metricCount),
+			SelfMetrics: make([]float64, agg.metricCount),
+		}
+		agg.stats[funcID] = stat
+	}
+	return stat
+}
+
+// createCallGraph creates the call graph structure
+func (agg *aggregator) createCallGraph(totalTime time.Duration, functions map[int]string) *CallGraph {
+	nodes := make(map[int]*CallNode)
+	stats := agg.stats
+
+	// Calculate total execution time
+	if totalTime <= 0 {
+		for _, stat := range stats {
+			if stat.TotalDuration > totalTime {
+				totalTime = stat.TotalDuration
+			}
+		}
+	}
+
+	// Create nodes
+	for funcID, stat := range stats {
+		funcName := shortFunctionName(functionName(functions, funcID))
+
+		percentage := 0.0
+		selfPercentage := 0.0
+		if totalTime > 0 {
+			percentage = float64(stat.TotalDuration) / float64(totalTime) * 100
+			selfPercentage = float64(stat.SelfDuration) / float64(totalTime) * 100
+		}
+
+		nodes[funcID] = &CallNode{
+			FunctionID:     funcID,
+			Name:           funcName,
+			TotalDuration:  stat.TotalDuration,
+			SelfDuration:   stat.SelfDuration,
+			CallCount:      stat.CallCount,
+			TotalMemory:    stat.TotalMemory,
+			Percentage:     percentage,
+			SelfPercentage: selfPercentage,
+			Metrics:        stat.Metrics,
+			SelfMetrics:    stat.SelfMetrics,
+		}
+	}
+
+	// Create edges
+	var edges []*CallEdge
+	for key, edge := range agg.edges {
+		percentage := 0.0
+		if totalTime > 0 {
+			percentage = float64(edge.duration) / float64(totalTime) * 100
+		}
+
+		edges = append(edges, &CallEdge{
+			From:          key.from,
+			To:            key.to,
+			CallCount:     edge.count,
+			TotalDuration: edge.duration,
+			Percentage:    percentage,
+			Metrics:       edge.metrics,
+		})
+	}
+
+	return &CallGraph{
+		Nodes: nodes,
+		Edges: edges,
+		Total: totalTime,
+		Stats: stats,
+	}
+}
+
+// shortFunctionName shortens long file paths to their base name for display
+func shortFunctionName(name string) string {
+	if len(name) > 50 {
+		parts := strings.Split(name, "/")
+		if len(parts) > 1 {
+			return ".../" + parts[len(parts)-1]
+		}
+	}
+	return name
+}
+
+// functionName returns the profile function name or a placeholder
+func functionName(functions map[int]string, funcID int) string {
+	if name := functions[funcID]; name != "" {
+		return name
+	}
+	if funcID == RootID {
+		return RootName
+	}
+	return fmt.Sprintf("func_%d", funcID)
+}
+
+// FunctionName returns the profile function name or a placeholder
+func (p *Profile) FunctionName(funcID int) string {
+	return functionName(p.Functions, funcID)
+}
+
+// FunctionName returns the full name of a function of the graph
+func (cg *CallGraph) FunctionName(funcID int) string {
+	if name := cg.Functions[funcID]; name != "" {
+		return name
+	}
+	if node := cg.Nodes[funcID]; node != nil {
+		return node.Name
+	}
+	return functionName(nil, funcID)
+}
@@ -0,0 +1,103 @@
+package spx
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// analyzeExample builds the call graph of an example profile
+func analyzeExample(t *testing.T, name string) *CallGraph {
+	t.Helper()
+	p, err := ParseProfile("../../examples/" + name)
+	if err != nil {
+		t.Fatalf("ParseProfile: %v", err)
+	}
+	return NewAnalyzer(p).BuildCallGraph()
+}
+
+func TestFilterClear(t *testing.T) {
+	cg := analyzeExample(t, "recursive.txt")
+
+	focus, err := NewFilter("Logger::", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	focused, err := cg.Filter(focus)
+	if err != nil {
+		t.Fatalf("Filter: %v", err)
+	}
+	if len(focused.Nodes) >= len(cg.Nodes) {
+		t.Fatalf("focused graph has %d nodes, want less than %d", len(focused.Nodes), len(cg.Nodes))
+	}
+
+	for _, empty := range []*Filter{nil, {}} {
+		cleared, err := focused.Filter(empty)
+		if err != nil {
+			t.Fatalf("Filter: %v", err)
+		}
+		if cleared.Total != cg.Total {
+			t.Errorf("cleared total = %v, want %v", cleared.Total, cg.Total)
+		}
+		if len(cleared.Nodes) != len(cg.Nodes) {
+			t.Errorf("cleared graph has %d nodes, want %d", len(cleared.Nodes), len(cg.Nodes))
+		}
+		for id, node := range cg.Nodes {
+			got := cleared.Nodes[id]
+			if got == nil || got.TotalDuration != node.TotalDuration || got.SelfDuration != node.SelfDuration {
+				t.Errorf("cleared node %s = %+v, want %+v", cg.FunctionName(id), got, node)
+			}
+		}
+	}
+}
+
+func TestBuildCallGraphRecursive(t *testing.T) {
+	cg := analyzeExample(t, "recursive.txt")
+	const factorial, multiply = 1, 2
+
+	// Nested activations are part of the outermost call
+	node := cg.Nodes[factorial]
+	if want := 1000 * time.Microsecond; node.TotalDuration != want {
+		t.Errorf("Math::factorial total = %v, want %v", node.TotalDuration, want)
+	}
+	if want := 100.0 * 2 / 3; math.Abs(node.Percentage-want) > 0.01 {
+		t.Errorf("Math::factorial percentage = %.2f, want %.2f", node.Percentage, want)
+	}
+	for id, node := range cg.Nodes {
+		if node.Percentage > 100 {
+			t.Errorf("%s percentage = %.2f, want at most 100", cg.FunctionName(id), node.Percentage)
+		}
+	}
+
+	// The recursive edge is counted at its outermost call: factorial#1 -> factorial#3 holds factorial#5
+	edges := make(map[edgeKey]*CallEdge)
+	for _, edge := range cg.Edges {
+		edges[edgeKey{from: edge.From, to: edge.To}] = edge
+	}
+	for _, tt := range []struct {
+		from, to int
+		calls    int
+		total    time.Duration
+	}{
+		{factorial, factorial, 2, 600 * time.Microsecond},
+		{factorial, multiply, 2, 200 * time.Microsecond},
+	} {
+		edge := edges[edgeKey{from: tt.from, to: tt.to}]
+		if edge == nil {
+			t.Errorf("missing edge %s -> %s", cg.FunctionName(tt.from), cg.FunctionName(tt.to))
+			continue
+		}
+		if edge.CallCount != tt.calls || edge.TotalDuration != tt.total {
+			t.Errorf("edge %s -> %s: %d calls, total %v, want %d, %v", cg.FunctionName(tt.from), cg.FunctionName(tt.to),
+				edge.CallCount, edge.TotalDuration, tt.calls, tt.total)
+		}
+	}
+
+	var topLevel time.Duration
+	for _, child := range cg.Contexts.Children {
+		topLevel += child.Duration
+	}
+	if topLevel > cg.Total {
+		t.Errorf("top level calls total = %v, want at most baseline %v", topLevel, cg.Total)
+	}
+}
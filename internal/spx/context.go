@@ -0,0 +1,102 @@
+package spx
+
+import "time"
+
+// ContextNode is a node of the calling context tree: a unique call path from the root.
+// All calls of the same function through the same path are aggregated in one node.
+type ContextNode struct {
+	FunctionID   int            `json:"function_id"` // -1 for the tree root
+	Parent       *ContextNode   `json:"-"`
+	Children     []*ContextNode `json:"children"` // in first call order
+	CallCount    int            `json:"call_count"`
+	Duration     time.Duration  `json:"duration"`      // inclusive
+	SelfDuration time.Duration  `json:"self_duration"` // exclusive
+	MaxDuration  time.Duration  `json:"max_duration"`  // longest single call
+	Memory       int64          `json:"memory"`        // sum of call memory deltas
+	Metrics      []float64      `json:"metrics"`       // inclusive value per metric
+	SelfMetrics  []float64      `json:"self_metrics"`  // exclusive value per metric
+
+	byFunction map[int]*ContextNode
+}
+
+// newContextRoot creates the synthetic root holding top level calls
+func newContextRoot() *ContextNode {
+	return &ContextNode{FunctionID: -1}
+}
+
+// child returns the node of a call to funcID from this path, creating it on first call
+func (n *ContextNode) child(funcID int) *ContextNode {
+	if c, ok := n.byFunction[funcID]; ok {
+		return c
+	}
+	if n.byFunction == nil {
+		n.byFunction = make(map[int]*ContextNode)
+	}
+
+	c := &ContextNode{FunctionID: funcID, Parent: n}
+	n.byFunction[funcID] = c
+	n.Children = append(n.Children, c)
+	return c
+}
+
+// add aggregates a closed frame of this call path
+func (n *ContextNode) add(frame *Frame) {
+	if n.Metrics == nil {
+		n.Metrics = make([]float64, len(frame.Metrics))
+		n.SelfMetrics = make([]float64, len(frame.Metrics))
+	}
+
+	n.CallCount++
+	n.Duration += frame.Duration
+	n.SelfDuration += frame.SelfDuration
+	n.Memory += frame.MemoryDelta
+	if frame.Duration > n.MaxDuration {
+		n.MaxDuration = frame.Duration
+	}
+
+	addMetrics(n.Metrics, frame.Metrics)
+	addMetrics(n.SelfMetrics, frame.SelfMetrics)
+}
+
+// remove detaches a child node
+func (n *ContextNode) remove(c *ContextNode) {
+	delete(n.byFunction, c.FunctionID)
+	for i, child := range n.Children {
+		if child == c {
+			n.Children = append(n.Children[:i], n.Children[i+1:]...)
+			break
+		}
+	}
+}
+
+// sum computes inclusive values of the subtree from self values
+func (n *ContextNode) sum() {
+	n.Duration = n.SelfDuration
+	n.Metrics = addValues(nil, n.SelfMetrics)
+	for _, c := range n.Children {
+		c.sum()
+		n.Duration += c.Duration
+		n.Metrics = addValues(n.Metrics, c.Metrics)
+	}
+}
+
+// Path returns function IDs from the top level call down to this node
+func (n *ContextNode) Path() []int {
+	var path []int
+	for c := n; c != nil && c.Parent != nil; c = c.Parent {
+		path = append(path, c.FunctionID)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// addValues adds src to dst, allocating dst on first use
+func addValues(dst, src []float64) []float64 {
+	if dst == nil && src != nil {
+		dst = make([]float64, len(src))
+	}
+	addMetrics(dst, src)
+	return dst
+}
@@ -0,0 +1,242 @@
+package spx
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// costMinFraction is the fraction of total cost below which approximated call paths are cut
+const costMinFraction = 0.0001
+
+// costProfile is a profile recorded as costs per function and per caller and callee, like
+// callgrind and XHProf profiles. Call paths used by filters and the flame graph are not
+// recorded, they are approximated by splitting callee costs in proportion to call costs.
+type costProfile struct {
+	metrics       []Metric
+	ids           map[string]int // function name -> ID
+	functionNames []string       // ID -> function name
+
+	self  map[int][]float64      // exclusive costs per function
+	calls map[edgeKey]*edgeStats // inclusive costs per call
+	total []float64              // profile totals, nil when unknown
+}
+
+func newCostProfile() *costProfile {
+	return &costProfile{
+		ids:   make(map[string]int),
+		self:  make(map[int][]float64),
+		calls: make(map[edgeKey]*edgeStats),
+	}
+}
+
+// profile returns the profile of functions and metrics, it has no events
+func (p *costProfile) profile(metadata *Metadata) *Profile {
+	profile := &Profile{
+		Functions: make(map[int]string, len(p.functionNames)),
+		Metrics:   p.metrics,
+		Metadata:  metadata,
+	}
+	for id, name := range p.functionNames {
+		profile.Functions[id] = name
+	}
+	return profile
+}
+
+// addSelf adds exclusive costs of a function
+func (p *costProfile) addSelf(fn int, costs []float64) {
+	self := p.self[fn]
+	if self == nil {
+		self = make([]float64, len(p.metrics))
+		p.self[fn] = self
+	}
+	addMetrics(self, costs)
+}
+
+// call returns the costs of calls from caller to callee
+func (p *costProfile) call(from, to int) *edgeStats {
+	key := edgeKey{from: from, to: to}
+	call := p.calls[key]
+	if call == nil {
+		call = &edgeStats{metrics: make([]float64, len(p.metrics))}
+		p.calls[key] = call
+	}
+	return call
+}
+
+// function returns the ID of a function name, assigning IDs in order of appearance
+func (p *costProfile) function(name string) int {
+	if id, ok := p.ids[name]; ok {
+		return id
+	}
+	id := len(p.functionNames)
+	p.ids[name] = id
+	p.functionNames = append(p.functionNames, name)
+	return id
+}
+
+// callGraph creates the call graph from exact function and call costs and approximated call paths
+func (p *costProfile) callGraph(profile *Profile) *CallGraph {
+	timeIndex := metricIndex(p.metrics, "wt")
+	memoryIndex := metricIndex(p.metrics, "zm")
+	duration := func(values []float64) time.Duration {
+		if timeIndex < 0 {
+			return 0
+		}
+		return time.Duration(values[timeIndex] * float64(time.Microsecond))
+	}
+
+	agg := &aggregator{
+		metricCount: len(p.metrics),
+		stats:       make(map[int]*FunctionStats),
+		edges:       p.calls,
+	}
+
+	// Inclusive costs are self costs plus costs of calls to other functions,
+	// recursive calls are already part of the self and call costs of the function
+	inclusive := make(map[int][]float64)
+	for id := range p.functionNames {
+		inclusive[id] = addValues(make([]float64, len(p.metrics)), p.self[id])
+	}
+	callCount := make(map[int]int)
+	for key, call := range p.calls {
+		call.duration = duration(call.metrics)
+		callCount[key.to] += call.count
+		if key.from != key.to {
+			addMetrics(inclusive[key.from], call.metrics)
+		}
+	}
+
+	for id := range p.functionNames {
+		self := addValues(make([]float64, len(p.metrics)), p.self[id])
+		stat := &FunctionStats{
+			TotalDuration: duration(inclusive[id]),
+			SelfDuration:  max(duration(self), 0),
+			CallCount:     max(callCount[id], 1), // functions without callers run once
+			Metrics:       inclusive[id],
+			SelfMetrics:   self,
+		}
+		if memoryIndex >= 0 {
+			stat.TotalMemory = int64(inclusive[id][memoryIndex])
+		}
+		agg.stats[id] = stat
+	}
+
+	// Entry points are functions nobody calls, like {main} of Xdebug or main() of XHProf
+	var entries []*ContextNode
+	for id := range p.functionNames {
+		if callCount[id] == 0 {
+			stat := agg.stats[id]
+			entries = append(entries, &ContextNode{
+				FunctionID: id,
+				CallCount:  stat.CallCount,
+				Duration:   stat.TotalDuration,
+				Memory:     stat.TotalMemory,
+				Metrics:    stat.Metrics,
+			})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].FunctionID < entries[j].FunctionID })
+
+	var total time.Duration
+	if p.total != nil {
+		total = duration(p.total)
+	} else {
+		for _, entry := range entries {
+			total += max(entry.Duration, 0)
+		}
+	}
+	root := agg.addRoot(total, entries)
+
+	callGraph := agg.createCallGraph(total, profile.Functions)
+	callGraph.Metrics = p.metrics
+	callGraph.Functions = profile.Functions
+	callGraph.Diagnostics = &Diagnostics{}
+
+	contexts := newContextRoot()
+	weight := func(values []float64) float64 {
+		if timeIndex >= 0 {
+			return values[timeIndex]
+		}
+		return values[0]
+	}
+	minWeight := 0.0
+	for _, entry := range entries {
+		minWeight += weight(entry.Metrics)
+	}
+	minWeight *= costMinFraction
+
+	calls := make(map[int][]edgeKey)
+	for key := range p.calls {
+		calls[key.from] = append(calls[key.from], key)
+	}
+
+	onPath := make(map[int]bool)
+	var expand func(node *ContextNode, fraction float64)
+	expand = func(node *ContextNode, fraction float64) {
+		id := node.FunctionID
+		stat := agg.stats[id]
+		onPath[id] = true
+
+		node.CallCount = max(int(math.Round(float64(stat.CallCount)*fraction)), 1)
+		node.SelfMetrics = scaleValues(stat.SelfMetrics, fraction)
+		node.SelfDuration = time.Duration(float64(stat.SelfDuration) * fraction)
+		node.Memory = int64(float64(stat.TotalMemory) * fraction)
+
+		// A call path gets the share of callee costs its caller path made
+		for _, key := range calls[id] {
+			if onPath[key.to] {
+				continue
+			}
+			call := p.calls[key]
+			callee := agg.stats[key.to]
+
+			share := 0.0
+			if w := weight(callee.Metrics); w > 0 {
+				share = weight(call.metrics) / w
+			} else if callee.CallCount > 0 {
+				share = float64(call.count) / float64(callee.CallCount)
+			}
+			childFraction := fraction * min(share, 1)
+			if weight(callee.Metrics)*childFraction < minWeight {
+				continue
+			}
+			expand(node.child(key.to), childFraction)
+		}
+
+		onPath[id] = false
+	}
+	for _, entry := range entries {
+		expand(contexts.child(entry.FunctionID), 1)
+	}
+	contexts.sum()
+
+	callGraph.Contexts = contexts
+	callGraph.calls = contexts
+
+	// Root is the synthetic root or the entry point with the highest cost
+	if root {
+		callGraph.Root = RootID
+		return callGraph
+	}
+	var heaviest *ContextNode
+	for _, child := range contexts.Children {
+		if heaviest == nil || weight(child.Metrics) > weight(heaviest.Metrics) {
+			heaviest = child
+		}
+	}
+	if heaviest != nil {
+		callGraph.Root = heaviest.FunctionID
+	}
+
+	return callGraph
+}
+
+// scaleValues returns values multiplied by factor
+func scaleValues(values []float64, factor float64) []float64 {
+	scaled := make([]float64, len(values))
+	for i, value := range values {
+		scaled[i] = value * factor
+	}
+	return scaled
+}
@@ -0,0 +1,66 @@
+package spx
+
+import "fmt"
+
+// maxDiagnosticMessages limits how many problems are described in detail
+const maxDiagnosticMessages = 10
+
+type problemKind int
+
+const (
+	problemUnmatchedExit problemKind = iota
+	problemMissingExit
+	problemUnclosedFrame
+)
+
+// problem is a recorded nesting problem, described once function names are known
+type problem struct {
+	kind          problemKind
+	event         int // event index
+	function      int // function of the event
+	frame         int // affected call
+	frameFunction int // function of the affected call
+}
+
+// OK reports whether every event line was read and events formed a well nested call tree
+func (d *Diagnostics) OK() bool {
+	return d.SkippedLines == 0 && d.UnmatchedExits == 0 && d.MissingExits == 0 && d.UnclosedFrames == 0
+}
+
+// String returns a short human readable report
+func (d *Diagnostics) String() string {
+	if d.OK() {
+		return "call tree is well nested"
+	}
+	report := fmt.Sprintf("%d unmatched exit events, %d calls without exit event, %d calls still open at end of profile",
+		d.UnmatchedExits, d.MissingExits, d.UnclosedFrames)
+	if d.SkippedLines > 0 {
+		report = fmt.Sprintf("%d event lines skipped, %s", d.SkippedLines, report)
+	}
+	return report
+}
+
+func (d *Diagnostics) addProblem(p problem) {
+	if len(d.problems) < maxDiagnosticMessages {
+		d.problems = append(d.problems, p)
+	}
+}
+
+// resolve describes skipped lines and recorded problems in Messages
+func (d *Diagnostics) resolve(name func(funcID int) string) {
+	d.Messages = make([]string, 0, len(d.skipMessages)+len(d.problems))
+	d.Messages = append(d.Messages, d.skipMessages...)
+	for _, p := range d.problems {
+		switch p.kind {
+		case problemUnmatchedExit:
+			d.Messages = append(d.Messages, fmt.Sprintf("event #%d: exit of %s without open call",
+				p.event, name(p.function)))
+		case problemMissingExit:
+			d.Messages = append(d.Messages, fmt.Sprintf("event #%d: exit of %s closes call #%d of %s without exit event",
+				p.event, name(p.function), p.frame, name(p.frameFunction)))
+		case problemUnclosedFrame:
+			d.Messages = append(d.Messages, fmt.Sprintf("call #%d of %s still open at end of profile",
+				p.frame, name(p.frameFunction)))
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package spx
+
+import (
+	"sort"
+	"time"
+)
+
+// DiffCallGraphs builds a call graph of deltas between base and head.
+// Nodes and edges are matched by full function name, values are head minus base
+// and percentages are relative to the base total time, like pprof -diff_base.
+func DiffCallGraphs(base, head *CallGraph) *CallGraph {
+	// Assign stable IDs to the union of function names
+	names := make(map[string]bool)
+	for id := range base.Nodes {
+		names[base.FunctionName(id)] = true
+	}
+	for id := range head.Nodes {
+		names[head.FunctionName(id)] = true
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	ids := make(map[string]int, len(sorted))
+	for i, name := range sorted {
+		ids[name] = i
+	}
+
+	// Metric columns of head, base values are matched by key
+	baseMetric := make([]int, len(head.Metrics))
+	for i, metric := range head.Metrics {
+		baseMetric[i] = base.MetricIndex(metric.Key)
+	}
+
+	diff := &CallGraph{
+		Nodes:     make(map[int]*CallNode, len(sorted)),
+		Metrics:   head.Metrics,
+		Total:     base.Total,
+		Diff:      true,
+		Functions: make(map[int]string, len(sorted)),
+	}
+
+	for _, name := range sorted {
+		diff.Functions[ids[name]] = name
+		diff.Nodes[ids[name]] = &CallNode{
+			FunctionID:  ids[name],
+			Name:        shortFunctionName(name),
+			Metrics:     make([]float64, len(head.Metrics)),
+			SelfMetrics: make([]float64, len(head.Metrics)),
+		}
+	}
+
+	for id, node := range head.Nodes {
+		d := diff.Nodes[ids[head.FunctionName(id)]]
+		d.TotalDuration += node.TotalDuration
+		d.SelfDuration += node.SelfDuration
+		d.CallCount += node.CallCount
+		d.TotalMemory += node.TotalMemory
+		addMetrics(d.Metrics, node.Metrics)
+		addMetrics(d.SelfMetrics, node.SelfMetrics)
+	}
+
+	for id, node := range base.Nodes {
+		d := diff.Nodes[ids[base.FunctionName(id)]]
+		d.TotalDuration -= node.TotalDuration
+		d.SelfDuration -= node.SelfDuration
+		d.CallCount -= node.CallCount
+		d.TotalMemory -= node.TotalMemory
+		subMetrics(d.Metrics, remapMetrics(node.Metrics, baseMetric))
+		subMetrics(d.SelfMetrics, remapMetrics(node.SelfMetrics, baseMetric))
+	}
+
+	for _, node := range diff.Nodes {
+		node.Percentage = diffPercentage(node.TotalDuration, base.Total)
+		node.SelfPercentage = diffPercentage(node.SelfDuration, base.Total)
+	}
+
+	// Edges
+	edges := make(map[edgeKey]*CallEdge)
+
+	edgeFor := func(graph *CallGraph, edge *CallEdge) *CallEdge {
+		if graph.Nodes[edge.From] == nil || graph.Nodes[edge.To] == nil {
+			return nil
+		}
+		key := edgeKey{from: ids[graph.FunctionName(edge.From)], to: ids[graph.FunctionName(edge.To)]}
+		if edges[key] == nil {
+			edges[key] = &CallEdge{
+				From:    key.from,
+				To:      key.to,
+				Metrics: make([]float64, len(head.Metrics)),
+			}
+		}
+		return edges[key]
+	}
+
+	for _, edge := range head.Edges {
+		if d := edgeFor(head, edge); d != nil {
+			d.CallCount += edge.CallCount
+			d.TotalDuration += edge.TotalDuration
+			addMetrics(d.Metrics, edge.Metrics)
+		}
+	}
+
+	for _, edge := range base.Edges {
+		if d := edgeFor(base, edge); d != nil {
+			d.CallCount -= edge.CallCount
+			d.TotalDuration -= edge.TotalDuration
+			subMetrics(d.Metrics, remapMetrics(edge.Metrics, baseMetric))
+		}
+	}
+
+	for _, edge := range edges {
+		edge.Percentage = diffPercentage(edge.TotalDuration, base.Total)
+		diff.Edges = append(diff.Edges, edge)
+	}
+
+	if head.Nodes[head.Root] != nil {
+		diff.Root = ids[head.FunctionName(head.Root)]
+	}
+
+	return diff
+}
+
+// remapMetrics reorders base metric values to head metric columns
+func remapMetrics(values []float64, index []int) []float64 {
+	result := make([]float64, len(index))
+	for i, j := range index {
+		if j >= 0 && j < len(values) {
+			result[i] = values[j]
+		}
+	}
+	return result
+}
+
+func diffPercentage(delta, total time.Duration) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(delta) / float64(total) * 100
+}
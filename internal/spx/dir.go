@@ -0,0 +1,76 @@
+package spx
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ProfileFile is a profile found in a SPX data directory
+type ProfileFile struct {
+	Key      string    // file name without extension
+	Path     string    // full path to the profile file
+	ModTime  time.Time // profile file modification time
+	Size     int64     // profile file size in bytes
+	Metadata *Metadata // nil when no metadata file found
+}
+
+// Time returns the profile execution time, falling back to file modification time
+func (f *ProfileFile) Time() time.Time {
+	if f.Metadata != nil && f.Metadata.ExecTs > 0 {
+		return f.Metadata.ExecTime()
+	}
+	return f.ModTime
+}
+
+// xdebugProfilePrefix starts the default file names of the Xdebug profiler
+const xdebugProfilePrefix = "cachegrind.out."
+
+// ListProfiles lists every .txt and .txt.gz profile in a SPX data directory,
+// as well as Xdebug cachegrind.out files
+func ListProfiles(dir string) ([]ProfileFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read directory: %w", err)
+	}
+
+	var files []ProfileFile
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		name := entry.Name()
+		xdebug := strings.HasPrefix(name, xdebugProfilePrefix)
+		if !xdebug && !strings.HasSuffix(name, ".txt") && !strings.HasSuffix(name, ".txt.gz") {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+
+		// Broken metadata should not hide the profile itself
+		metadata, _ := findMetadata(path)
+
+		key := strings.TrimSuffix(name, ".gz")
+		if !xdebug {
+			key = strings.TrimSuffix(key, ".txt")
+		}
+
+		files = append(files, ProfileFile{
+			Key:      key,
+			Path:     path,
+			ModTime:  info.ModTime(),
+			Size:     info.Size(),
+			Metadata: metadata,
+		})
+	}
+
+	return files, nil
+}
@@ -0,0 +1,177 @@
+package spx
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Filter restricts a call graph like pprof focus, ignore, hide and show options.
+// Every call path is a sample holding the self values of its calls:
+//   - Focus keeps samples with a function matching Focus on their path
+//   - Ignore drops samples with a function matching Ignore on their path
+//   - Hide removes matching functions from paths, their self values go to the caller
+//   - Show removes every function not matching Show from paths
+type Filter struct {
+	Focus  *regexp.Regexp
+	Ignore *regexp.Regexp
+	Hide   *regexp.Regexp
+	Show   *regexp.Regexp
+}
+
+// NewFilter compiles filter expressions, empty expressions are not applied
+func NewFilter(focus, ignore, hide, show string) (*Filter, error) {
+	filter := &Filter{}
+
+	for _, f := range []struct {
+		name string
+		expr string
+		re   **regexp.Regexp
+	}{
+		{"focus", focus, &filter.Focus},
+		{"ignore", ignore, &filter.Ignore},
+		{"hide", hide, &filter.Hide},
+		{"show", show, &filter.Show},
+	} {
+		if f.expr == "" {
+			continue
+		}
+		re, err := regexp.Compile(f.expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s expression: %w", f.name, err)
+		}
+		*f.re = re
+	}
+
+	return filter, nil
+}
+
+// IsEmpty reports whether the filter keeps the graph unchanged
+func (f *Filter) IsEmpty() bool {
+	return f == nil || (f.Focus == nil && f.Ignore == nil && f.Hide == nil && f.Show == nil)
+}
+
+// String returns the filter expressions in flag form
+func (f *Filter) String() string {
+	if f.IsEmpty() {
+		return ""
+	}
+
+	var parts []string
+	for _, expr := range []struct {
+		name string
+		re   *regexp.Regexp
+	}{
+		{"focus", f.Focus},
+		{"ignore", f.Ignore},
+		{"hide", f.Hide},
+		{"show", f.Show},
+	} {
+		if expr.re != nil {
+			parts = append(parts, expr.name+"="+expr.re.String())
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// filterMatch is the filter result for one function
+type filterMatch struct {
+	focus  bool
+	ignore bool
+	hidden bool
+}
+
+func (f *Filter) match(name string) filterMatch {
+	if f.IsEmpty() {
+		return filterMatch{}
+	}
+	return filterMatch{
+		focus:  f.Focus != nil && f.Focus.MatchString(name),
+		ignore: f.Ignore != nil && f.Ignore.MatchString(name),
+		hidden: (f.Hide != nil && f.Hide.MatchString(name)) || (f.Show != nil && !f.Show.MatchString(name)),
+	}
+}
+
+// apply returns the calling context tree restricted by the filter.
+// Hidden functions are removed from paths, so their callees become callees of the
+// nearest visible caller and their self values are added to that caller.
+// Self values of hidden top level calls stay on the tree root.
+func (f *Filter) apply(contexts *ContextNode, functions map[int]string) *ContextNode {
+	if f.IsEmpty() {
+		return contexts
+	}
+
+	a := &filterApply{
+		filter:    f,
+		functions: functions,
+		matches:   make(map[int]filterMatch),
+	}
+
+	root := newContextRoot()
+	for _, child := range contexts.Children {
+		a.walk(child, root, false, false)
+	}
+	root.sum()
+
+	return root
+}
+
+// filterApply copies kept call paths into a new calling context tree
+type filterApply struct {
+	filter    *Filter
+	functions map[int]string
+	matches   map[int]filterMatch
+}
+
+// walk copies the subtree of n under out, the node of its nearest visible caller.
+// It reports whether any value of the subtree was kept.
+func (a *filterApply) walk(n *ContextNode, out *ContextNode, focused, ignored bool) bool {
+	m, ok := a.matches[n.FunctionID]
+	if !ok {
+		m = a.filter.match(functionName(a.functions, n.FunctionID))
+		a.matches[n.FunctionID] = m
+	}
+	focused = focused || m.focus
+	ignored = ignored || m.ignore
+	kept := !ignored && (a.filter.Focus == nil || focused)
+
+	target := out
+	created := false
+	if !m.hidden {
+		created = out.byFunction[n.FunctionID] == nil
+		target = out.child(n.FunctionID)
+	}
+
+	found := false
+	if kept {
+		target.SelfDuration += n.SelfDuration
+		target.SelfMetrics = addValues(target.SelfMetrics, n.SelfMetrics)
+		found = true
+	}
+
+	for _, child := range n.Children {
+		if a.walk(child, target, focused, ignored) {
+			found = true
+		}
+	}
+
+	if m.hidden {
+		return found
+	}
+
+	if !found {
+		// Nothing was kept, drop the node created for this path
+		if created {
+			out.remove(target)
+		}
+		return false
+	}
+
+	target.CallCount += n.CallCount
+	target.Memory += n.Memory
+	if n.MaxDuration > target.MaxDuration {
+		target.MaxDuration = n.MaxDuration
+	}
+
+	return true
+}
@@ -0,0 +1,108 @@
+package spx
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFilterRecursive(t *testing.T) {
+	cg := analyzeExample(t, "recursive.txt")
+
+	type values struct {
+		total, self time.Duration
+	}
+	us := time.Microsecond
+
+	tests := []struct {
+		name                      string
+		focus, ignore, hide, show string
+		want                      map[string]values // every function left with its inclusive and self time
+	}{
+		{
+			name: "empty filter keeps unfiltered totals",
+			want: map[string]values{
+				"main.php":        {1500 * us, 400 * us},
+				"Math::factorial": {1000 * us, 800 * us},
+				"Math::multiply":  {200 * us, 200 * us},
+				"Logger::log":     {100 * us, 100 * us},
+			},
+		},
+		{
+			name: "hide moves self time to the caller",
+			hide: "Math::multiply",
+			want: map[string]values{
+				"main.php":        {1500 * us, 400 * us},
+				"Math::factorial": {1000 * us, 1000 * us},
+				"Logger::log":     {100 * us, 100 * us},
+			},
+		},
+		{
+			name:  "focus keeps ancestors and descendants",
+			focus: "Math::multiply",
+			want: map[string]values{
+				"main.php":        {200 * us, 0},
+				"Math::factorial": {200 * us, 0},
+				"Math::multiply":  {200 * us, 200 * us},
+			},
+		},
+		{
+			name:   "ignore drops whole subtrees",
+			ignore: "Math::factorial",
+			want: map[string]values{
+				"main.php":    {500 * us, 400 * us},
+				"Logger::log": {100 * us, 100 * us},
+			},
+		},
+		{
+			name: "show keeps matching functions only",
+			show: "Math::",
+			want: map[string]values{
+				"Math::factorial": {1000 * us, 800 * us},
+				"Math::multiply":  {200 * us, 200 * us},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewFilter(tt.focus, tt.ignore, tt.hide, tt.show)
+			if err != nil {
+				t.Fatal(err)
+			}
+			filtered, err := cg.Filter(filter)
+			if err != nil {
+				t.Fatalf("Filter: %v", err)
+			}
+
+			// Percentages keep the unfiltered baseline
+			if filtered.Total != cg.Total {
+				t.Errorf("total = %v, want %v", filtered.Total, cg.Total)
+			}
+			if len(filtered.Nodes) != len(tt.want) {
+				t.Errorf("got %d functions, want %d", len(filtered.Nodes), len(tt.want))
+			}
+			for id, node := range filtered.Nodes {
+				name := filtered.FunctionName(id)
+				want, ok := tt.want[name]
+				if !ok {
+					t.Errorf("unexpected function %s", name)
+					continue
+				}
+				if node.TotalDuration != want.total || node.SelfDuration != want.self {
+					t.Errorf("%s: total = %v, self = %v, want %v, %v",
+						name, node.TotalDuration, node.SelfDuration, want.total, want.self)
+				}
+			}
+
+			// Clearing the filter restores the unfiltered graph
+			cleared, err := filtered.Filter(nil)
+			if err != nil {
+				t.Fatalf("Filter: %v", err)
+			}
+			if cleared.Total != cg.Total || len(cleared.Nodes) != len(cg.Nodes) {
+				t.Errorf("cleared graph: %d functions, total %v, want %d, %v",
+					len(cleared.Nodes), cleared.Total, len(cg.Nodes), cg.Total)
+			}
+		})
+	}
+}
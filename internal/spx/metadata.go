@@ -0,0 +1,97 @@
+package spx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Metadata describes the SPX run, SPX writes it as JSON next to the profile
+type Metadata struct {
+	Key                 string   `json:"key"`
+	ExecTs              int64    `json:"exec_ts"` // unix timestamp
+	HostName            string   `json:"host_name"`
+	ProcessPID          int      `json:"process_pid"`
+	ProcessTID          int      `json:"process_tid"`
+	ProcessPwd          string   `json:"process_pwd"`
+	PHPVersion          string   `json:"php_version"`
+	CLI                 jsonBool `json:"cli"`
+	CLICommandLine      string   `json:"cli_command_line"`
+	HTTPMethod          string   `json:"http_method"`
+	HTTPHost            string   `json:"http_host"`
+	HTTPRequestURI      string   `json:"http_request_uri"`
+	WallTimeMs          float64  `json:"wall_time_ms"`
+	PeakMemoryUsage     int64    `json:"peak_memory_usage"` // bytes
+	CalledFunctionCount int      `json:"called_function_count"`
+	CallCount           int      `json:"call_count"`
+	RecordedCallCount   int      `json:"recorded_call_count"`
+	EnabledMetrics      []string `json:"enabled_metrics"`
+}
+
+// jsonBool accepts both JSON booleans and 0/1 numbers
+type jsonBool bool
+
+func (b *jsonBool) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case "true", "1":
+		*b = true
+	case "false", "0", "null":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean: %s", data)
+	}
+	return nil
+}
+
+// ExecTime returns the time the profiled script started
+func (m *Metadata) ExecTime() time.Time {
+	return time.Unix(m.ExecTs, 0)
+}
+
+// WallTime returns the total script execution time
+func (m *Metadata) WallTime() time.Duration {
+	return time.Duration(m.WallTimeMs * float64(time.Millisecond))
+}
+
+// Target returns the HTTP request or the CLI command line of the run
+func (m *Metadata) Target() string {
+	if m.CLI {
+		return m.CLICommandLine
+	}
+	return strings.TrimSpace(m.HTTPMethod + " " + m.HTTPRequestURI)
+}
+
+// metadataPath returns the metadata file path for a profile file
+// e.g. spx-full-xxx.txt.gz -> spx-full-xxx.json
+func metadataPath(filename string) string {
+	base := strings.TrimSuffix(filename, ".gz")
+	base = strings.TrimSuffix(base, ".txt")
+	return base + ".json"
+}
+
+// ParseMetadata parse SPX metadata JSON file
+func ParseMetadata(filename string) (*Metadata, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read metadata: %w", err)
+	}
+
+	var metadata Metadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("invalid metadata %s: %w", filename, err)
+	}
+
+	return &metadata, nil
+}
+
+// findMetadata loads the metadata file next to the profile, if it exists
+func findMetadata(profileFile string) (*Metadata, error) {
+	metadata, err := ParseMetadata(metadataPath(profileFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return metadata, err
+}
@@ -0,0 +1,101 @@
+package spx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Metric units
+const (
+	UnitMicroseconds = "us"
+	UnitBytes        = "bytes"
+	UnitCount        = "count"
+)
+
+// Metric describes one metric column of the SPX events section
+type Metric struct {
+	Key  string `json:"key"`  // SPX short name, e.g. "wt" or "zm"
+	Name string `json:"name"` // human readable name
+	Unit string `json:"unit"`
+}
+
+// knownMetrics lists SPX metrics in the order SPX writes them
+var knownMetrics = []Metric{
+	{Key: "wt", Name: "Wall time", Unit: UnitMicroseconds},
+	{Key: "ct", Name: "CPU time", Unit: UnitMicroseconds},
+	{Key: "it", Name: "Idle time", Unit: UnitMicroseconds},
+	{Key: "zm", Name: "ZE memory usage", Unit: UnitBytes},
+	{Key: "zmac", Name: "ZE allocation count", Unit: UnitCount},
+	{Key: "zmab", Name: "ZE allocated bytes", Unit: UnitBytes},
+	{Key: "zmfc", Name: "ZE free count", Unit: UnitCount},
+	{Key: "zmfb", Name: "ZE freed bytes", Unit: UnitBytes},
+	{Key: "zgr", Name: "ZE GC runs", Unit: UnitCount},
+	{Key: "zgb", Name: "ZE GC root buffer length", Unit: UnitCount},
+	{Key: "zgc", Name: "ZE GC collected cycles", Unit: UnitCount},
+	{Key: "zif", Name: "ZE included files", Unit: UnitCount},
+	{Key: "zil", Name: "ZE included lines", Unit: UnitCount},
+	{Key: "zuc", Name: "ZE user classes", Unit: UnitCount},
+	{Key: "zuf", Name: "ZE user functions", Unit: UnitCount},
+	{Key: "zuo", Name: "ZE user opcodes", Unit: UnitCount},
+	{Key: "zo", Name: "ZE object count", Unit: UnitCount},
+	{Key: "ze", Name: "ZE error count", Unit: UnitCount},
+	{Key: "io", Name: "I/O bytes", Unit: UnitBytes},
+	{Key: "ior", Name: "I/O read bytes", Unit: UnitBytes},
+	{Key: "iow", Name: "I/O written bytes", Unit: UnitBytes},
+}
+
+// DefaultMetrics are the metrics SPX records when none are configured
+var DefaultMetrics = []string{"wt", "zm"}
+
+// LookupMetric returns the metric description for a SPX metric key
+func LookupMetric(key string) Metric {
+	for _, m := range knownMetrics {
+		if m.Key == key {
+			return m
+		}
+	}
+	return Metric{Key: key, Name: key, Unit: UnitCount}
+}
+
+// MetricIndex returns the column index of the metric with given key or -1
+func (p *Profile) MetricIndex(key string) int {
+	return metricIndex(p.Metrics, key)
+}
+
+// MetricIndex returns the index of the metric with given key or -1
+func (cg *CallGraph) MetricIndex(key string) int {
+	return metricIndex(cg.Metrics, key)
+}
+
+func metricIndex(metrics []Metric, key string) int {
+	for i, m := range metrics {
+		if m.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// metricKeys returns metric keys separated by commas
+func metricKeys(metrics []Metric) string {
+	keys := make([]string, len(metrics))
+	for i, m := range metrics {
+		keys[i] = m.Key
+	}
+	return strings.Join(keys, ",")
+}
+
+// guessMetrics names event columns when the profile does not describe them.
+// SPX default metrics are assumed first, extra columns get generic names.
+func guessMetrics(count int) []Metric {
+	metrics := make([]Metric, count)
+	for i := range metrics {
+		if i < len(DefaultMetrics) {
+			metrics[i] = LookupMetric(DefaultMetrics[i])
+		} else {
+			key := fmt.Sprintf("m%d", i)
+			metrics[i] = Metric{Key: key, Name: key, Unit: UnitCount}
+		}
+	}
+	return metrics
+}
@@ -4,8 +4,8 @@ import (
 	"bufio"
 	"compress/gzip"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -18,75 +18,90 @@ func ParseProfile(filename string) (*Profile, error) {
 	}
 	defer file.Close()
 
-	var scanner *bufio.Scanner
+	metadata, err := findMetadata(filename)
+	if err != nil {
+		return nil, err
+	}
 
-	if filepath.Ext(filename) == ".gz" {
-		gzReader, err := gzip.NewReader(file)
-		if err != nil {
-			return nil, fmt.Errorf("cannot create gzip reader: %w", err)
-		}
-		defer gzReader.Close()
-		scanner = bufio.NewScanner(gzReader)
-	} else {
-		scanner = bufio.NewScanner(file)
+	return parseReader(file, metadata)
+}
+
+// ParseReader parse spx profile from a reader, gzip compression is detected automatically
+func ParseReader(r io.Reader) (*Profile, error) {
+	return parseReader(r, nil)
+}
+
+func parseReader(r io.Reader, metadata *Metadata) (*Profile, error) {
+	decompressed, closer, err := decompress(r)
+	if err != nil {
+		return nil, err
 	}
+	defer closer()
 
-	profile := &Profile{
-		Events:    make([]Event, 0),
-		Functions: make(map[int]string),
+	// Callgrind and XHProf files have no events, only their call graph can be analyzed
+	reader := bufio.NewReader(decompressed)
+	if isCallgrind(reader) || isXHProf(reader) {
+		return nil, fmt.Errorf("callgrind and XHProf profiles have no events to parse, only graph views support them")
 	}
 
-	inEvents := false
-	inFunctions := false
-	functionIndex := 0
+	profile := &Profile{
+		Events:   make([]Event, 0),
+		Metadata: metadata,
+	}
 
+	scanner := NewScanner(reader, metadataMetrics(metadata))
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+		profile.Events = append(profile.Events, scanner.Event())
+	}
 
-		if line == "" {
-			continue
-		}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
 
-		// switch sections
-		if line == "[events]" {
-			inEvents = true
-			inFunctions = false
-			continue
-		}
+	profile.Functions = scanner.Functions()
+	profile.Metrics = scanner.Metrics()
+	profile.EventCount = scanner.EventCount()
+	profile.SkippedLines = scanner.SkippedLines()
+	profile.skipMessages = scanner.skipMessages
 
-		if line == "[functions]" {
-			inEvents = false
-			inFunctions = true
-			continue
-		}
+	return profile, nil
+}
 
-		// parse events
-		if inEvents {
-			event, err := parseEvent(line)
-			if err != nil {
-				continue // пропускаем неверные строки
-			}
-			profile.Events = append(profile.Events, event)
-		}
+// decompress wraps gzip streams, detected by magic bytes, in a gzip reader
+func decompress(r io.Reader) (io.Reader, func(), error) {
+	buffered := bufio.NewReader(r)
 
-		// parse functions
-		if inFunctions {
-			profile.Functions[functionIndex] = line
-			functionIndex++
-		}
+	magic, err := buffered.Peek(2)
+	if err != nil || magic[0] != 0x1f || magic[1] != 0x8b {
+		// not gzip, short and empty input are left to the scanner
+		return buffered, func() {}, nil
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading file: %w", err)
+	gzReader, err := gzip.NewReader(buffered)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot create gzip reader: %w", err)
 	}
 
-	return profile, nil
+	return gzReader, func() { gzReader.Close() }, nil
+}
+
+// metadataMetrics returns event columns described by metadata, nil when unknown
+func metadataMetrics(metadata *Metadata) []Metric {
+	if metadata == nil || len(metadata.EnabledMetrics) == 0 {
+		return nil
+	}
+
+	metrics := make([]Metric, len(metadata.EnabledMetrics))
+	for i, key := range metadata.EnabledMetrics {
+		metrics[i] = LookupMetric(key)
+	}
+	return metrics
 }
 
-// parseEvent parse event
+// parseEvent parse event line: function ID, event type and one value per metric
 func parseEvent(line string) (Event, error) {
 	parts := strings.Fields(line)
-	if len(parts) != 4 {
+	if len(parts) < 3 {
 		return Event{}, fmt.Errorf("invalid event format: %s", line)
 	}
 
@@ -100,20 +115,18 @@ func parseEvent(line string) (Event, error) {
 		return Event{}, fmt.Errorf("invalid event type: %s", parts[1])
 	}
 
-	time, err := strconv.ParseInt(parts[2], 10, 64)
-	if err != nil {
-		return Event{}, fmt.Errorf("invalid time: %s", parts[2])
-	}
-
-	memory, err := strconv.ParseInt(parts[3], 10, 64)
-	if err != nil {
-		return Event{}, fmt.Errorf("invalid memory: %s", parts[3])
+	metrics := make([]float64, len(parts)-2)
+	for i, part := range parts[2:] {
+		value, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return Event{}, fmt.Errorf("invalid metric value: %s", part)
+		}
+		metrics[i] = value
 	}
 
 	return Event{
 		FunctionID: functionID,
 		EventType:  eventType,
-		Time:       time,
-		Memory:     memory,
+		Metrics:    metrics,
 	}, nil
 }
@@ -0,0 +1,185 @@
+package spx
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Scanner reads SPX profile events one at a time, like bufio.Scanner.
+// SPX writes [events] before [functions], so function names are only
+// available from Functions once Scan returned false.
+type Scanner struct {
+	reader    *bufio.Reader
+	metrics   []Metric
+	functions map[int]string
+	event     Event
+	count     int
+	err       error
+
+	line         int      // line number of the last line read
+	skipped      int      // event lines that are not valid events
+	skipMessages []string // why the first event lines were skipped
+
+	inEvents      bool
+	inFunctions   bool
+	functionIndex int
+
+	wt, zm int // wall time and memory columns
+}
+
+// NewScanner creates an event scanner. metrics names event columns,
+// when nil they are guessed from the first event line.
+func NewScanner(r io.Reader, metrics []Metric) *Scanner {
+	s := &Scanner{
+		reader:    bufio.NewReaderSize(r, 64*1024),
+		functions: make(map[int]string),
+	}
+	s.setMetrics(metrics)
+	return s
+}
+
+func (s *Scanner) setMetrics(metrics []Metric) {
+	s.metrics = metrics
+	s.wt = metricIndex(metrics, "wt")
+	s.zm = metricIndex(metrics, "zm")
+}
+
+// Scan advances to the next event, it returns false at the end of input or on error
+func (s *Scanner) Scan() bool {
+	for s.err == nil {
+		line, err := readLine(s.reader)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				s.err = fmt.Errorf("error reading profile: %w", err)
+			} else if s.count == 0 && s.skipped > 0 {
+				s.err = fmt.Errorf("no valid event in profile, %d event lines skipped: %s", s.skipped, s.skipMessages[0])
+			}
+			return false
+		}
+		s.line++
+
+		line = strings.TrimSpace(line)
+
+		if line == "" {
+			continue
+		}
+
+		// switch sections
+		if line == "[events]" {
+			s.inEvents = true
+			s.inFunctions = false
+			continue
+		}
+
+		if line == "[functions]" {
+			s.inEvents = false
+			s.inFunctions = true
+			continue
+		}
+
+		// parse functions
+		if s.inFunctions {
+			s.functions[s.functionIndex] = line
+			s.functionIndex++
+			continue
+		}
+
+		// parse events
+		if s.inEvents {
+			event, err := parseEvent(line)
+			if err != nil {
+				s.skip(err.Error())
+				continue
+			}
+			if s.metrics == nil {
+				s.setMetrics(guessMetrics(len(event.Metrics)))
+			}
+			if s.wt < 0 {
+				s.err = fmt.Errorf("no wall time (wt) column in event metrics %q, call times cannot be computed",
+					metricKeys(s.metrics))
+				return false
+			}
+			if len(event.Metrics) != len(s.metrics) {
+				s.skip(fmt.Sprintf("%d metric values, expected %d (%s)",
+					len(event.Metrics), len(s.metrics), metricKeys(s.metrics)))
+				continue
+			}
+			if s.wt >= 0 {
+				event.Time = int64(event.Metrics[s.wt])
+			}
+			if s.zm >= 0 {
+				event.Memory = int64(event.Metrics[s.zm])
+			}
+
+			s.event = event
+			s.count++
+			return true
+		}
+	}
+	return false
+}
+
+// skip records an event line that is not a valid event
+func (s *Scanner) skip(reason string) {
+	s.skipped++
+	if len(s.skipMessages) < maxDiagnosticMessages {
+		s.skipMessages = append(s.skipMessages, fmt.Sprintf("line %d: %s", s.line, reason))
+	}
+}
+
+// readLine reads a whole line without length limit
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadSlice('\n')
+	if err == nil || (errors.Is(err, io.EOF) && len(line) > 0) {
+		return string(line), nil
+	}
+	if !errors.Is(err, bufio.ErrBufferFull) {
+		return "", err
+	}
+
+	// Line is longer than the buffer, collect it in chunks
+	var buf bytes.Buffer
+	buf.Write(line)
+	for errors.Is(err, bufio.ErrBufferFull) {
+		line, err = reader.ReadSlice('\n')
+		buf.Write(line)
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Event returns the event read by the last Scan call
+func (s *Scanner) Event() Event {
+	return s.event
+}
+
+// Err returns the first read error
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// Metrics returns event metric columns
+func (s *Scanner) Metrics() []Metric {
+	return s.metrics
+}
+
+// Functions returns function names by ID, complete once Scan returned false
+func (s *Scanner) Functions() map[int]string {
+	return s.functions
+}
+
+// EventCount returns the number of events read so far
+func (s *Scanner) EventCount() int {
+	return s.count
+}
+
+// SkippedLines returns the number of event lines skipped so far because they are not valid events
+func (s *Scanner) SkippedLines() int {
+	return s.skipped
+}
@@ -0,0 +1,45 @@
+package spx
+
+import (
+	"strings"
+	"testing"
+)
+
+// scanAll reads every event of a profile
+func scanAll(profile string, metrics []Metric) (*Scanner, []Event) {
+	scanner := NewScanner(strings.NewReader(profile), metrics)
+	var events []Event
+	for scanner.Scan() {
+		events = append(events, scanner.Event())
+	}
+	return scanner, events
+}
+
+func TestScannerWithoutWallTime(t *testing.T) {
+	metrics := []Metric{LookupMetric("ct"), LookupMetric("zm")}
+	scanner, events := scanAll("[events]\n0 1 0 0\n0 0 10 0\n", metrics)
+
+	if len(events) != 0 {
+		t.Errorf("got %d events, want none", len(events))
+	}
+	if err := scanner.Err(); err == nil || !strings.Contains(err.Error(), "no wall time (wt) column") {
+		t.Errorf("error = %v, want missing wall time column", err)
+	}
+}
+
+func TestScannerColumnCount(t *testing.T) {
+	scanner, events := scanAll("[events]\n0 1 0 0\n1 1 10 0 5\n1 0 20 0\n0 0 30 0\n", nil)
+
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	if len(events) != 3 {
+		t.Errorf("got %d events, want 3", len(events))
+	}
+	if scanner.SkippedLines() != 1 {
+		t.Errorf("skipped %d lines, want 1", scanner.SkippedLines())
+	}
+	if want := "line 3: 3 metric values, expected 2 (wt,zm)"; len(scanner.skipMessages) != 1 || scanner.skipMessages[0] != want {
+		t.Errorf("skip messages = %q, want %q", scanner.skipMessages, want)
+	}
+}
@@ -0,0 +1,70 @@
+package spx
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+)
+
+// AnalyzeReader builds the call graph of a profile read from r without keeping
+// events in memory. Metadata may be nil, it names the event metric columns.
+// The returned profile has functions, metrics and metadata but no events.
+// Gzip compression is detected automatically, as well as callgrind files of the Xdebug profiler
+// and XHProf or Tideways JSON profiles.
+func AnalyzeReader(r io.Reader, metadata *Metadata) (*Profile, *CallGraph, error) {
+	decompressed, closer, err := decompress(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer closer()
+
+	reader := bufio.NewReader(decompressed)
+	if isCallgrind(reader) {
+		return analyzeCallgrind(reader, metadata)
+	}
+	if isXHProf(reader) {
+		return analyzeXHProf(reader, metadata)
+	}
+
+	profile := &Profile{Metadata: metadata}
+	analyzer := NewAnalyzer(profile)
+
+	builder := newTreeBuilder(false)
+
+	scanner := NewScanner(reader, metadataMetrics(metadata))
+	for scanner.Scan() {
+		event := scanner.Event()
+		builder.push(&event)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	builder.finish()
+
+	// [functions] section comes after events, names are resolved now
+	profile.Functions = scanner.Functions()
+	profile.Metrics = scanner.Metrics()
+	profile.EventCount = scanner.EventCount()
+	profile.SkippedLines = scanner.SkippedLines()
+	profile.skipMessages = scanner.skipMessages
+
+	return profile, analyzer.createCallGraph(builder.tree), nil
+}
+
+// AnalyzeFile streams a profile file and its metadata into a call graph, see AnalyzeReader
+func AnalyzeFile(filename string) (*Profile, *CallGraph, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot open file: %w", err)
+	}
+	defer file.Close()
+
+	metadata, err := findMetadata(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return AnalyzeReader(file, metadata)
+}
@@ -0,0 +1,25 @@
+package spx
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func BenchmarkAnalyzeReader(b *testing.B) {
+	for _, shape := range []string{"deep", "wide"} {
+		for _, n := range benchmarkSizes {
+			b.Run(fmt.Sprintf("%s/%d", shape, n), func(b *testing.B) {
+				profile := generateProfile(n, shape)
+				b.SetBytes(int64(len(profile)))
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, _, err := AnalyzeReader(strings.NewReader(profile), nil); err != nil {
+						b.Fatalf("AnalyzeReader: %v", err)
+					}
+				}
+			})
+		}
+	}
+}
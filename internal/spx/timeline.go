@@ -0,0 +1,75 @@
+package spx
+
+import (
+	"sort"
+	"time"
+)
+
+// maxTimelineSpans bounds the calls kept on the timeline of large profiles
+const maxTimelineSpans = 200000
+
+// Span is a single call on the timeline
+type Span struct {
+	FunctionID int   `json:"function_id"`
+	Depth      int   `json:"depth"`
+	Start      int64 `json:"start"` // microseconds
+	End        int64 `json:"end"`   // microseconds
+	Truncated  bool  `json:"truncated"`
+}
+
+// Timeline keeps calls in chronological order.
+// When a profile has too many calls the shortest ones are dropped, MinDuration
+// is then the duration below which calls are not on the timeline.
+type Timeline struct {
+	Start       int64  `json:"start"` // first event time, microseconds
+	End         int64  `json:"end"`   // last event time, microseconds
+	Spans       []Span `json:"spans"` // ordered by start time
+	MinDuration int64  `json:"min_duration"`
+	Dropped     int    `json:"dropped"` // calls shorter than MinDuration
+}
+
+// Duration returns the time between first and last event
+func (t *Timeline) Duration() time.Duration {
+	return time.Duration(t.End-t.Start) * time.Microsecond
+}
+
+// add records a closed frame, dropping the shortest calls when there are too many
+func (t *Timeline) add(frame *Frame) {
+	if frame.EndTime-frame.StartTime < t.MinDuration {
+		t.Dropped++
+		return
+	}
+
+	t.Spans = append(t.Spans, Span{
+		FunctionID: frame.FunctionID,
+		Depth:      frame.Depth,
+		Start:      frame.StartTime,
+		End:        frame.EndTime,
+		Truncated:  frame.Truncated,
+	})
+
+	// A frame is never shorter than its calls, so no call is kept without its caller
+	for len(t.Spans) > maxTimelineSpans {
+		t.MinDuration = max(t.MinDuration*2, 1)
+
+		kept := t.Spans[:0]
+		for _, span := range t.Spans {
+			if span.End-span.Start >= t.MinDuration {
+				kept = append(kept, span)
+			} else {
+				t.Dropped++
+			}
+		}
+		t.Spans = kept
+	}
+}
+
+// sort orders spans by start time, callers before their calls
+func (t *Timeline) sort() {
+	sort.SliceStable(t.Spans, func(i, j int) bool {
+		if t.Spans[i].Start != t.Spans[j].Start {
+			return t.Spans[i].Start < t.Spans[j].Start
+		}
+		return t.Spans[i].Depth < t.Spans[j].Depth
+	})
+}
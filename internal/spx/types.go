@@ -3,31 +3,86 @@ package spx
 import "time"
 
 type Event struct {
-	FunctionID int   `json:"function_id"`
-	EventType  int   `json:"event_type"` // 1 = enter, 0 = exit
-	Time       int64 `json:"time"`       // microseconds
-	Memory     int64 `json:"memory"`     // bytes
+	FunctionID int       `json:"function_id"`
+	EventType  int       `json:"event_type"` // 1 = enter, 0 = exit
+	Time       int64     `json:"time"`       // microseconds
+	Memory     int64     `json:"memory"`     // bytes
+	Metrics    []float64 `json:"metrics"`    // one value per Profile.Metrics entry
 }
 
 type Profile struct {
 	Events    []Event        `json:"events"`
 	Functions map[int]string `json:"functions"` // ID -> function name
+	Metrics   []Metric       `json:"metrics"`   // event metric columns
+	Metadata  *Metadata      `json:"metadata"`  // nil when no metadata file found
+
+	// EventCount is the number of events read, also set when events are streamed
+	EventCount int `json:"event_count"`
+	// SkippedLines is the number of event lines that could not be read as events
+	SkippedLines int `json:"skipped_lines"`
+
+	skipMessages []string // why the first event lines were skipped
+}
+
+// Frame is a single function call of the reconstructed call tree
+type Frame struct {
+	ID           int           `json:"id"` // unique frame ID in enter order
+	FunctionID   int           `json:"function_id"`
+	Parent       *Frame        `json:"-"` // nil for root frames
+	Children     []*Frame      `json:"children"`
+	Depth        int           `json:"depth"`
+	Duration     time.Duration `json:"duration"`      // execution duration
+	SelfDuration time.Duration `json:"self_duration"` // duration excluding child calls
+	MemoryDelta  int64         `json:"memory_delta"`  // memory change
+	StartTime    int64         `json:"start_time"`
+	EndTime      int64         `json:"end_time"`
+	StartEvent   int           `json:"start_event"`  // index of the entry event
+	EndEvent     int           `json:"end_event"`    // index of the event closing the call
+	Metrics      []float64     `json:"metrics"`      // inclusive value per metric
+	SelfMetrics  []float64     `json:"self_metrics"` // exclusive value per metric
+	Truncated    bool          `json:"truncated"`    // closed without its exit event
+
+	context *ContextNode // call path of the frame
 }
 
-type CallInfo struct {
-	FunctionID  int           `json:"function_id"`
-	Parent      *int          `json:"parent"`       // parent function ID
-	Duration    time.Duration `json:"duration"`     // execution duration
-	MemoryDelta int64         `json:"memory_delta"` // memory change
-	StartTime   int64         `json:"start_time"`
-	EndTime     int64         `json:"end_time"`
-	Children    []int         `json:"children"` // child function IDs
+// CallTree is the call tree reconstructed from profile events
+type CallTree struct {
+	Roots       []*Frame     `json:"roots"`
+	Frames      []*Frame     `json:"-"`        // indexed by frame ID
+	Contexts    *ContextNode `json:"contexts"` // calls aggregated per call path
+	Timeline    *Timeline    `json:"timeline"` // calls in chronological order
+	Diagnostics *Diagnostics `json:"diagnostics"`
+}
+
+// Diagnostics reports event lines that were skipped and events that do not form a well nested call tree
+type Diagnostics struct {
+	SkippedLines   int      `json:"skipped_lines"`   // event lines that could not be read as events
+	UnmatchedExits int      `json:"unmatched_exits"` // exit events without an open call of the function
+	MissingExits   int      `json:"missing_exits"`   // calls closed by an exit of an outer call
+	UnclosedFrames int      `json:"unclosed_frames"` // calls still open at the end of events
+	Messages       []string `json:"messages"`        // first problems found
+
+	problems     []problem
+	skipMessages []string
 }
 
 type CallGraph struct {
-	Nodes map[int]*CallNode `json:"nodes"`
-	Edges []*CallEdge       `json:"edges"`
-	Root  int               `json:"root"`
+	Nodes   map[int]*CallNode `json:"nodes"`
+	Edges   []*CallEdge       `json:"edges"`
+	Root    int               `json:"root"`
+	Metrics []Metric          `json:"metrics"`
+	Total   time.Duration     `json:"total"` // percentage baseline
+	Diff    bool              `json:"diff"`  // values are deltas against a base profile
+
+	Functions map[int]string         `json:"functions"` // ID -> full function name
+	Stats     map[int]*FunctionStats `json:"-"`         // ID -> aggregated statistics
+	Contexts  *ContextNode           `json:"-"`         // call paths of the graph, nil when unknown, e.g. diff graphs
+	Timeline  *Timeline              `json:"-"`         // calls in chronological order, nil when unknown
+
+	Diagnostics *Diagnostics `json:"diagnostics"`
+
+	calls      *ContextNode // unfiltered call paths
+	unfiltered *CallGraph   // graph the filtered graph was built from, nil when not filtered
 }
 
 type CallNode struct {
@@ -39,6 +94,8 @@ type CallNode struct {
 	TotalMemory    int64         `json:"total_memory"`
 	Percentage     float64       `json:"percentage"` // percentage of total time
 	SelfPercentage float64       `json:"self_percentage"`
+	Metrics        []float64     `json:"metrics"`      // inclusive value per metric
+	SelfMetrics    []float64     `json:"self_metrics"` // exclusive value per metric
 }
 
 type CallEdge struct {
@@ -47,6 +104,7 @@ type CallEdge struct {
 	CallCount     int           `json:"call_count"`
 	TotalDuration time.Duration `json:"total_duration"`
 	Percentage    float64       `json:"percentage"`
+	Metrics       []float64     `json:"metrics"` // inclusive value per metric
 }
 
 type FunctionStats struct {
@@ -55,4 +113,6 @@ type FunctionStats struct {
 	CallCount     int
 	TotalMemory   int64
 	MaxDuration   time.Duration
+	Metrics       []float64
+	SelfMetrics   []float64
 }
@@ -0,0 +1,145 @@
+package spx
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// xhprofRoot is the entry point of XHProf and Tideways profiles
+const xhprofRoot = "main()"
+
+// xhprofMetrics maps XHProf value keys to SPX metrics, in column order
+var xhprofMetrics = []struct {
+	key    string
+	metric Metric
+}{
+	{"wt", LookupMetric("wt")},
+	{"cpu", LookupMetric("ct")},
+	{"mu", LookupMetric("zm")},
+	{"pmu", Metric{Key: "pmu", Name: "Peak memory usage", Unit: UnitBytes}},
+}
+
+// isXHProf reports whether buffered input starts like a JSON document
+func isXHProf(r *bufio.Reader) bool {
+	for i := 1; ; i++ {
+		head, err := r.Peek(i)
+		if err != nil || len(head) < i {
+			return false
+		}
+		switch head[i-1] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '{':
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// analyzeXHProf builds the call graph of a XHProf or Tideways JSON profile: a map of
+// "parent==>child" keys to inclusive ct, wt, cpu, mu and pmu values of the calls.
+// The profile may be wrapped in a "profile" field, as stored by XHGui.
+func analyzeXHProf(reader *bufio.Reader, metadata *Metadata) (*Profile, *CallGraph, error) {
+	var document map[string]json.RawMessage
+	if err := json.NewDecoder(reader).Decode(&document); err != nil {
+		return nil, nil, fmt.Errorf("invalid XHProf profile: %w", err)
+	}
+	if wrapped, ok := document["profile"]; ok {
+		document = nil
+		if err := json.Unmarshal(wrapped, &document); err != nil {
+			return nil, nil, fmt.Errorf("invalid XHProf profile: %w", err)
+		}
+	}
+
+	entries := make(map[string]map[string]float64, len(document))
+	for key, raw := range document {
+		var values map[string]float64
+		if err := json.Unmarshal(raw, &values); err != nil {
+			return nil, nil, fmt.Errorf("invalid XHProf entry %s: %w", key, err)
+		}
+		entries[key] = values
+	}
+
+	// Only metrics recorded by the profiler become columns
+	p := newCostProfile()
+	var keys []string
+	for _, m := range xhprofMetrics {
+		for _, values := range entries {
+			if _, ok := values[m.key]; ok {
+				p.metrics = append(p.metrics, m.metric)
+				keys = append(keys, m.key)
+				break
+			}
+		}
+	}
+	if len(p.metrics) == 0 {
+		return nil, nil, fmt.Errorf("invalid XHProf profile: no wt, cpu, mu or pmu values")
+	}
+
+	// Sorted entries give stable function IDs, main() first
+	names := make([]string, 0, len(entries))
+	for key := range entries {
+		names = append(names, key)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if (names[i] == xhprofRoot) != (names[j] == xhprofRoot) {
+			return names[i] == xhprofRoot
+		}
+		return names[i] < names[j]
+	})
+
+	// Inclusive values of a function are the sum of its calls,
+	// self values are inclusive values minus calls it made
+	inclusive := make(map[int][]float64)
+	for _, name := range names {
+		values := entries[name]
+		costs := make([]float64, len(keys))
+		for k, key := range keys {
+			costs[k] = values[key]
+		}
+
+		parent, child, isCall := strings.Cut(name, "==>")
+		if !isCall {
+			child = parent
+		}
+		callee := xhprofFunction(child)
+		to := p.function(callee)
+		inclusive[to] = addValues(inclusive[to], costs)
+		p.addSelf(to, costs)
+
+		if isCall {
+			caller := xhprofFunction(parent)
+			from := p.function(caller)
+			call := p.call(from, to)
+			call.count += int(values["ct"])
+			// Calls between two recursive activations, like "fib@1==>fib@2", are part of the
+			// inclusive values of the outermost call of the edge, like for SPX call edges
+			if caller == parent || callee == child {
+				addMetrics(call.metrics, costs)
+			}
+			p.addSelf(from, scaleValues(costs, -1))
+		}
+	}
+
+	if root, ok := p.ids[xhprofRoot]; ok {
+		p.total = inclusive[root]
+	}
+
+	profile := p.profile(metadata)
+	return profile, p.callGraph(profile), nil
+}
+
+// xhprofFunction removes the recursion depth suffix XHProf adds to recursive calls, like "fib@2"
+func xhprofFunction(name string) string {
+	if i := strings.LastIndexByte(name, '@'); i > 0 {
+		depth := name[i+1:]
+		if depth != "" && strings.Trim(depth, "0123456789") == "" {
+			return name[:i]
+		}
+	}
+	return name
+}